@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 
@@ -59,6 +60,11 @@ func main() {
 		//fang.WithNotifySignal(os.Interrupt, os.Kill),
 		fang.WithoutVersion(),
 	); err != nil {
+		// commands like rousego report the exit status of their processes
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 
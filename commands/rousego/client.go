@@ -0,0 +1,214 @@
+package rousego
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// controlClient talks to the control socket of a running rousego
+type controlClient struct {
+	http *http.Client
+	path string
+}
+
+func newControlClient(dir string) *controlClient {
+	return newSocketClient(socketPath(dir))
+}
+
+func newSocketClient(path string) *controlClient {
+	return &controlClient{
+		path: path,
+		http: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// clientForConfig returns the client for the rousego which runs the config
+// file that would be used by "rousego" in the current directory
+func clientForConfig() (*controlClient, error) {
+	path, err := findConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return newControlClient(filepath.Dir(path)), nil
+}
+
+func (c *controlClient) do(method, path string) (*http.Response, error) {
+	req, err := http.NewRequest(method, "http://rousego"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, errors.New("rousego is not running (no control socket at " + c.path + ")")
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Error != "" {
+			return nil, errors.New(body.Error)
+		}
+		return nil, errors.New(resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *controlClient) ps() ([]processStatus, error) {
+	resp, err := c.do(http.MethodGet, "/ps")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list []processStatus
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (c *controlClient) action(action, label string) error {
+	resp, err := c.do(http.MethodPost, "/"+action+"/"+url.PathEscape(label))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (c *controlClient) logs(w io.Writer, label string, follow bool, lines int) error {
+	path := "/logs"
+	if label != "" {
+		path += "/" + url.PathEscape(label)
+	}
+	query := url.Values{}
+	query.Set("follow", strconv.FormatBool(follow))
+	if lines >= 0 {
+		query.Set("lines", strconv.Itoa(lines))
+	}
+
+	resp, err := c.do(http.MethodGet, path+"?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength+1)
+	for scanner.Scan() {
+		fmt.Fprintln(w, scanner.Text())
+	}
+	return scanner.Err()
+}
+
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "show the processes of the running rousego",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := clientForConfig()
+		if err != nil {
+			return err
+		}
+		list, err := c.ps()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LABEL\tSTATE\tPID\tRESTARTS\tUPTIME\tRSS\tCPU\tREADY\tHEALTH\tLAST EXIT\tNEXT RUN")
+		for _, s := range list {
+			pid, uptime, rss, cpu := "-", "-", "-", "-"
+			if s.Pid > 0 {
+				pid = strconv.Itoa(s.Pid)
+				uptime = s.Uptime.Truncate(time.Second).String()
+				rss = formatBytes(s.RSS)
+				cpu = strconv.FormatFloat(s.CPU, 'f', 1, 64) + "%"
+			}
+			next := "-"
+			if !s.NextRun.IsZero() {
+				next = s.NextRun.Local().Format("15:04:05") + " (runs: " + strconv.Itoa(s.Runs) + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Label, s.State, pid, s.Restarts, uptime, rss, cpu, orDash(s.Ready), orDash(s.Health), orDash(s.LastExit), next)
+		}
+		return w.Flush()
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// actionCmd returns a subcommand which sends action for one process
+func actionCmd(action, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   action + " <label>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := clientForConfig()
+			if err != nil {
+				return err
+			}
+			return c.action(action, args[0])
+		},
+	}
+}
+
+var (
+	logsFollow bool
+	logsLines  int
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [label]",
+	Short: "show the output of a process of the running rousego, or of all processes",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := clientForConfig()
+		if err != nil {
+			return err
+		}
+		label := ""
+		if len(args) > 0 {
+			label = args[0]
+		}
+		return c.logs(os.Stdout, label, logsFollow, logsLines)
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "keep streaming new lines")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", -1, "number of buffered lines to show (default: all)")
+
+	Cmd.AddCommand(psCmd)
+	Cmd.AddCommand(actionCmd("start", "start a process of the running rousego"))
+	Cmd.AddCommand(actionCmd("stop", "stop a process of the running rousego"))
+	Cmd.AddCommand(actionCmd("restart", "restart a process of the running rousego"))
+	Cmd.AddCommand(logsCmd)
+}
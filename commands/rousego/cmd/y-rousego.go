@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -48,6 +49,10 @@ func main() {
 		//fang.WithNotifySignal(os.Interrupt, os.Kill),
 	); err != nil {
 		slog.Error(fmt.Sprint(err))
+		var exitErr interface{ ExitCode() int }
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
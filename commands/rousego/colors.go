@@ -0,0 +1,45 @@
+package rousego
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// colorPattern accepts hex colors like "#f80" or "#ff8800" and ANSI color
+// numbers from 0 to 255
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3}|#[0-9a-fA-F]{6}|[0-9]|[1-9][0-9]|1[0-9][0-9]|2[0-4][0-9]|25[0-5])$`)
+
+func validateColor(label, color string) error {
+	if !colorPattern.MatchString(color) {
+		return fmt.Errorf("[%s] invalid color %q (expected e.g. \"#ff8800\" or an ANSI color number)", label, color)
+	}
+	return nil
+}
+
+// labelColor returns the color of a label. The hue is derived from a hash of
+// the label, so a label keeps its color no matter how many commands there
+// are. The lightness depends on the background of the terminal.
+func labelColor(label string) lipgloss.TerminalColor {
+	h := fnv.New32a()
+	h.Write([]byte(label))
+	hue := float64(h.Sum32() % 360)
+
+	return lipgloss.AdaptiveColor{
+		Light: colorful.Hcl(hue, 0.7, 0.45).Clamped().Hex(),
+		Dark:  colorful.Hcl(hue, 0.6, 0.75).Clamped().Hex(),
+	}
+}
+
+// labelStyle returns the style of the [label] prefix. lipgloss drops colors
+// for NO_COLOR and if the output is no terminal.
+func labelStyle(c cfgCommands) lipgloss.Style {
+	var color lipgloss.TerminalColor = labelColor(c.Label)
+	if c.Color != "" {
+		color = lipgloss.Color(c.Color)
+	}
+	return lipgloss.NewStyle().Bold(true).Foreground(color)
+}
@@ -0,0 +1,196 @@
+package rousego
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// composeNames are the default names of a compose file in the order docker
+// compose looks for them
+var composeNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// composeFile is the part of a compose file which maps to local processes.
+// The fields have several forms, so they are converted by hand.
+type composeFile struct {
+	Services map[string]struct {
+		Command     any `yaml:"command"`
+		Environment any `yaml:"environment"`
+		EnvFile     any `yaml:"env_file"`
+		DependsOn   any `yaml:"depends_on"`
+	} `yaml:"services"`
+}
+
+// readCompose converts the services of a compose file into commands. Services
+// without a command only run an image and are skipped, like dependencies on
+// them.
+func readCompose(b []byte) (cmds []cfgCommands, skipped []string, err error) {
+	var f composeFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, nil, err
+	}
+
+	names := make([]string, 0, len(f.Services))
+	for name, s := range f.Services {
+		if s.Command == nil {
+			skipped = append(skipped, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	slices.Sort(skipped)
+
+	for _, name := range names {
+		s := f.Services[name]
+		c := cfgCommands{Label: name}
+
+		switch v := s.Command.(type) {
+		case string:
+			c.Cmd = composeUnescape.Replace(v)
+		case []any:
+			c.Args = composeStrings(v)
+		default:
+			return nil, nil, fmt.Errorf("service %s: invalid command", name)
+		}
+
+		switch v := s.Environment.(type) {
+		case nil:
+		case map[string]any:
+			c.Env = make(map[string]string)
+			for k, val := range v {
+				// variables without value are passed through from the environment
+				if val != nil {
+					c.Env[k] = composeUnescape.Replace(fmt.Sprint(val))
+				}
+			}
+		case []any:
+			c.Env = make(map[string]string)
+			for _, kv := range composeStrings(v) {
+				if k, val, ok := strings.Cut(kv, "="); ok {
+					c.Env[k] = val
+				}
+			}
+		default:
+			return nil, nil, fmt.Errorf("service %s: invalid environment", name)
+		}
+
+		var envFiles []string
+		switch v := s.EnvFile.(type) {
+		case nil:
+		case string:
+			envFiles = []string{v}
+		case []any:
+			envFiles = composeStrings(v)
+		default:
+			return nil, nil, fmt.Errorf("service %s: invalid env_file", name)
+		}
+		if len(envFiles) > 1 {
+			return nil, nil, fmt.Errorf("service %s: only one env_file is supported", name)
+		}
+		if len(envFiles) == 1 {
+			c.EnvFile = envFiles[0]
+		}
+
+		var deps []string
+		switch v := s.DependsOn.(type) {
+		case nil:
+		case []any:
+			deps = composeStrings(v)
+		case map[string]any:
+			for dep := range v {
+				deps = append(deps, dep)
+			}
+			slices.Sort(deps)
+		default:
+			return nil, nil, fmt.Errorf("service %s: invalid depends_on", name)
+		}
+		for _, dep := range deps {
+			if !slices.Contains(skipped, dep) {
+				c.DependsOn = append(c.DependsOn, dep)
+			}
+		}
+
+		cmds = append(cmds, c)
+	}
+	return cmds, skipped, nil
+}
+
+// composeUnescape turns the escaped $ of compose into a plain one. $${ is
+// kept, it is the escape of rousego for ${.
+var composeUnescape = strings.NewReplacer("$${", "$${", "$$", "$")
+
+func composeStrings(list []any) []string {
+	s := make([]string, len(list))
+	for i, v := range list {
+		s[i] = composeUnescape.Replace(fmt.Sprint(v))
+	}
+	return s
+}
+
+var importComposeCmd = &cobra.Command{
+	Use:   "compose [path]",
+	Short: "convert the services of a compose file into a rousego.toml",
+	Long: `Convert the services of a compose file into a rousego.toml next to it with
+one [[cmds]] entry per service. command, environment, env_file and depends_on
+are converted. The commands run as local processes, not in containers, so
+services without a command, which only run an image, are skipped. Images,
+builds, ports, volumes and networks are ignored.
+
+Without path, compose.yaml, compose.yml, docker-compose.yaml or
+docker-compose.yml in the current directory is converted.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := ""
+		if len(args) > 0 {
+			path = args[0]
+		} else {
+			for _, name := range composeNames {
+				if _, err := os.Stat(name); err == nil {
+					path = name
+					break
+				}
+			}
+			if path == "" {
+				return errors.New("no compose file found, expected one of " + strings.Join(composeNames, ", "))
+			}
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+		cmds, skipped, err := readCompose(b)
+		if err != nil {
+			return fmt.Errorf("could not convert %s: %w", path, err)
+		}
+		for _, name := range skipped {
+			fmt.Fprintf(os.Stderr, "Skipping service %s: no command\n", name)
+		}
+		if len(cmds) == 0 {
+			return errors.New("no services with a command defined in " + path)
+		}
+
+		target := importTarget(path)
+		if dir := importDir(path, target); dir != "" {
+			for i, c := range cmds {
+				cmds[i].Dir = dir
+				if c.EnvFile != "" && !filepath.IsAbs(c.EnvFile) {
+					cmds[i].EnvFile = filepath.Join(dir, c.EnvFile)
+				}
+			}
+		}
+		return writeImport(path, "compose", target, struct {
+			Cmds []cfgCommands `toml:"cmds"`
+		}{cmds}, len(cmds))
+	},
+}
+
+func init() {
+	importCmd.AddCommand(importComposeCmd)
+}
@@ -0,0 +1,123 @@
+package rousego
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestReadCompose(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        []cfgCommands
+		wantSkipped []string
+		wantErr     bool
+	}{
+		{
+			name: "command forms",
+			content: `
+services:
+  web:
+    command: npm start -- --port $$PORT
+  worker:
+    command: ["node", "worker.js", 2]
+`,
+			want: []cfgCommands{
+				{Label: "web", Cmd: "npm start -- --port $PORT"},
+				{Label: "worker", Args: []string{"node", "worker.js", "2"}},
+			},
+		},
+		{
+			name: "environment as map and list",
+			content: `
+services:
+  a:
+    command: a
+    environment:
+      PORT: 8080
+      DEBUG: "true"
+      PASSED:
+  b:
+    command: b
+    environment:
+      - PORT=8081
+      - PASSED
+`,
+			want: []cfgCommands{
+				{Label: "a", Cmd: "a", Env: map[string]string{"PORT": "8080", "DEBUG": "true"}},
+				{Label: "b", Cmd: "b", Env: map[string]string{"PORT": "8081"}},
+			},
+		},
+		{
+			name: "env file",
+			content: `
+services:
+  a:
+    command: a
+    env_file: .env
+  b:
+    command: b
+    env_file: [dev.env]
+`,
+			want: []cfgCommands{
+				{Label: "a", Cmd: "a", EnvFile: ".env"},
+				{Label: "b", Cmd: "b", EnvFile: "dev.env"},
+			},
+		},
+		{
+			name: "dependencies on skipped services are dropped",
+			content: `
+services:
+  db:
+    image: postgres
+  api:
+    command: api
+    depends_on: [db, worker]
+  web:
+    command: web
+    depends_on:
+      api:
+        condition: service_started
+      db:
+        condition: service_healthy
+  worker:
+    command: worker
+`,
+			want: []cfgCommands{
+				{Label: "api", Cmd: "api", DependsOn: []string{"worker"}},
+				{Label: "web", Cmd: "web", DependsOn: []string{"api"}},
+				{Label: "worker", Cmd: "worker"},
+			},
+			wantSkipped: []string{"db"},
+		},
+		{
+			name:    "escaped variable is kept for rousego",
+			content: "services:\n  a:\n    command: echo $${HOME} $$$$\n",
+			want:    []cfgCommands{{Label: "a", Cmd: "echo $${HOME} $$"}},
+		},
+		{name: "several env files", content: "services:\n  a:\n    command: a\n    env_file: [a.env, b.env]\n", wantErr: true},
+		{name: "invalid command", content: "services:\n  a:\n    command: {x: 1}\n", wantErr: true},
+		{name: "invalid yaml", content: "services: [", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, skipped, err := readCompose([]byte(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("readCompose() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readCompose() = %+v, want %+v", got, tt.want)
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.wantSkipped)
+			}
+		})
+	}
+}
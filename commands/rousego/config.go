@@ -0,0 +1,349 @@
+package rousego
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	restartNo        = "no"
+	restartOnFailure = "on-failure"
+	restartAlways    = "always"
+)
+
+const (
+	typeService = "service"
+	typeOneshot = "oneshot"
+)
+
+const (
+	defaultBackoff    = 1 * time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+type cfgMain struct {
+	// Include lists config files whose commands, env, vars and profiles are
+	// added to this one. Relative paths are relative to this file
+	Include []string `toml:"include,omitempty"`
+	// Vars are available as ${name} in cmd, args, dir and env values
+	Vars map[string]string `toml:"vars,omitempty"`
+	// Env applies to all commands
+	Env map[string]string `toml:"env,omitempty"`
+	// Before and After are commands which run one after another before the
+	// first and after the last process
+	Before []string `toml:"before,omitempty"`
+	After  []string `toml:"after,omitempty"`
+	// Output configures the combined output of all processes
+	Output cfgOutput `toml:"output,omitempty"`
+	// Logging writes the output of the processes to log files
+	Logging *cfgLogging `toml:"logging,omitempty"`
+	// Profiles are named selections of commands
+	Profiles map[string]cfgProfile `toml:"profiles,omitempty"`
+	Cmds     []cfgCommands         `toml:"cmds,omitempty"`
+
+	// file is the path of the config file, dir its directory
+	file string
+	dir  string
+}
+
+type cfgCommands struct {
+	Label string `toml:"label,omitempty"`
+	// Type is "service" (default) for long-running commands or "oneshot" for
+	// setup tasks. Dependents of a oneshot wait until it exited successfully
+	Type string `toml:"type,omitempty"`
+	// Cmd is run by Shell. Alternatively Args is executed directly without shell
+	Cmd   string   `toml:"cmd,omitempty"`
+	Args  []string `toml:"args,omitempty"`
+	Shell []string `toml:"shell,omitempty"`
+
+	// Instances starts the command several times, labeled "<label>#<n>".
+	// Port is the port of the first instance, the following instances get
+	// the next ports. Without Port a free port is picked for every instance
+	Instances int `toml:"instances,omitempty"`
+	Port      int `toml:"port,omitempty"`
+	// base is the label of the command an instance belongs to
+	base string
+	// configDir is the directory of the config file defining the command
+	configDir string
+
+	// Groups allow to select several commands with --only and --except
+	Groups []string `toml:"groups,omitempty"`
+
+	Dir     string            `toml:"dir,omitempty"`
+	Env     map[string]string `toml:"env,omitempty"`
+	EnvFile string            `toml:"env_file,omitempty"`
+
+	// DependsOn lists labels of commands which have to be started first
+	DependsOn []string `toml:"depends_on,omitempty"`
+	// Ready is an optional readiness probe. Dependent commands wait until it succeeds
+	Ready *cfgReady `toml:"ready,omitempty"`
+	// Healthcheck checks the process periodically once it is ready and
+	// restarts it when it is unhealthy
+	Healthcheck *cfgHealth `toml:"healthcheck,omitempty"`
+
+	// Restart is one of "no" (default), "on-failure" or "always"
+	Restart string `toml:"restart,omitempty"`
+	// MaxRestarts limits the number of restarts. 0 means unlimited
+	MaxRestarts int `toml:"max_restarts,omitempty"`
+	// Backoff is the delay before the first restart. It doubles with every
+	// further restart up to MaxBackoff
+	Backoff    duration `toml:"backoff,omitempty"`
+	MaxBackoff duration `toml:"max_backoff,omitempty"`
+
+	// StopSignal is sent to the process group on shutdown (default SIGTERM).
+	// Processes still running after StopTimeout are killed with SIGKILL
+	StopSignal  string   `toml:"stop_signal,omitempty"`
+	StopTimeout duration `toml:"stop_timeout,omitempty"`
+	stopSignal  syscall.Signal
+	// ForwardSignals lists the signals rousego passes on to the process
+	// group when it receives them, SIGUSR1 and SIGUSR2
+	ForwardSignals []string `toml:"forward_signals,omitempty"`
+	forwardSignals []syscall.Signal
+
+	// LogFile is the log file of the command. It overrides the file in the
+	// logging directory and works without [logging] table
+	LogFile string `toml:"log_file,omitempty"`
+
+	// Limits restricts memory, CPU time and open files of the command
+	Limits *cfgLimits `toml:"limits,omitempty"`
+
+	// Pty runs the command in a pseudo-terminal, so it keeps colors and
+	// progress output. stdout and stderr are merged
+	Pty bool `toml:"pty,omitempty"`
+
+	// Color overrides the color derived from the label
+	Color string `toml:"color,omitempty"`
+
+	// Schedule is a cron expression like "*/5 * * * *", Every an interval
+	// like "30s". Scheduled commands run again and again, but never twice
+	// at the same time
+	Schedule string   `toml:"schedule,omitempty"`
+	Every    duration `toml:"every,omitempty"`
+	schedule cron.Schedule
+
+	// Watch restarts the process when files change
+	Watch *cfgWatch `toml:"watch,omitempty"`
+	// watchBase is the directory changed files are shown relative to
+	watchBase string
+}
+
+// commandLine returns the command as it is shown in the output
+func (c *cfgCommands) commandLine() string {
+	if len(c.Args) > 0 {
+		return strings.Join(c.Args, " ")
+	}
+	return c.Cmd
+}
+
+// duration allows durations like "300ms" or "2s" in rousego.toml
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// validate checks the whole configuration including the dependency graph
+func (cfg *cfgMain) validate() error {
+	if len(cfg.Cmds) < 1 {
+		return errors.New("no commands defined in " + filepath.Base(cfg.file))
+	}
+
+	if err := cfg.Output.validate(); err != nil {
+		return err
+	}
+	if cfg.Logging != nil {
+		if err := cfg.Logging.validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, hook := range append(append([]string{}, cfg.Before...), cfg.After...) {
+		if strings.TrimSpace(hook) == "" {
+			return errors.New("before and after hooks must not be empty")
+		}
+	}
+
+	for i := range cfg.Cmds {
+		// the top-level env is the default for every command
+		env := make(map[string]string)
+		for k, v := range cfg.Env {
+			env[k] = v
+		}
+		for k, v := range cfg.Cmds[i].Env {
+			env[k] = v
+		}
+		cfg.Cmds[i].Env = env
+	}
+
+	cmds, err := expandInstances(cfg.Cmds)
+	if err != nil {
+		return err
+	}
+	cfg.Cmds = cmds
+
+	labels := make(map[string]bool)
+	for i := range cfg.Cmds {
+		if err := cfg.Cmds[i].validate(); err != nil {
+			return err
+		}
+		if labels[cfg.Cmds[i].Label] {
+			return fmt.Errorf("[%s] label is used more than once", cfg.Cmds[i].Label)
+		}
+		labels[cfg.Cmds[i].Label] = true
+	}
+
+	for _, c := range cfg.Cmds {
+		for _, dep := range c.DependsOn {
+			if dep == c.Label {
+				return fmt.Errorf("[%s] depends on itself", c.Label)
+			}
+			if !labels[dep] {
+				return fmt.Errorf("[%s] depends on unknown command %q", c.Label, dep)
+			}
+		}
+	}
+
+	if cycle := findCycle(cfg.Cmds); cycle != nil {
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	for name, p := range cfg.Profiles {
+		if err := checkNames(cfg.Cmds, append(append([]string{}, p.Only...), p.Except...)); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the command configuration and fills in defaults
+func (c *cfgCommands) validate() error {
+	if c.Label == "" {
+		return fmt.Errorf("command %q has no label", c.Cmd)
+	}
+
+	switch {
+	case c.Cmd == "" && len(c.Args) == 0:
+		return fmt.Errorf("[%s] needs either cmd or args", c.Label)
+	case c.Cmd != "" && len(c.Args) > 0:
+		return fmt.Errorf("[%s] cmd and args must not be used together", c.Label)
+	case len(c.Args) > 0 && len(c.Shell) > 0:
+		return fmt.Errorf("[%s] shell is not used together with args", c.Label)
+	}
+	if len(c.Args) == 0 && len(c.Shell) == 0 {
+		c.Shell = defaultShell
+	}
+
+	switch c.Restart {
+	case "":
+		c.Restart = restartNo
+	case restartNo, restartOnFailure, restartAlways:
+	default:
+		return fmt.Errorf("[%s] invalid restart policy %q (expected %q, %q or %q)", c.Label, c.Restart, restartNo, restartOnFailure, restartAlways)
+	}
+
+	switch c.Type {
+	case "":
+		c.Type = typeService
+	case typeService:
+	case typeOneshot:
+		if c.Restart == restartAlways {
+			return fmt.Errorf("[%s] a oneshot command cannot use restart = %q", c.Label, restartAlways)
+		}
+		if c.Ready != nil {
+			return fmt.Errorf("[%s] a oneshot command is ready when it exited successfully and has no ready probe", c.Label)
+		}
+		if c.Healthcheck != nil {
+			return fmt.Errorf("[%s] a oneshot command has no healthcheck", c.Label)
+		}
+	default:
+		return fmt.Errorf("[%s] invalid type %q (expected %q or %q)", c.Label, c.Type, typeService, typeOneshot)
+	}
+
+	if c.Ready != nil {
+		if err := c.Ready.validate(c.Label); err != nil {
+			return err
+		}
+	}
+
+	if c.Healthcheck != nil {
+		if err := c.Healthcheck.validate(c.Label); err != nil {
+			return err
+		}
+	}
+
+	if c.Limits != nil {
+		if err := c.Limits.validate(c.Label); err != nil {
+			return err
+		}
+	}
+
+	if err := c.validateSchedule(); err != nil {
+		return err
+	}
+
+	if c.Color != "" {
+		if err := validateColor(c.Label, c.Color); err != nil {
+			return err
+		}
+	}
+
+	if c.Watch != nil {
+		if err := c.Watch.validate(c.Label); err != nil {
+			return err
+		}
+	}
+
+	if c.StopSignal == "" {
+		c.StopSignal = "SIGTERM"
+	}
+	sig, err := parseSignal(c.StopSignal)
+	if err != nil {
+		return fmt.Errorf("[%s] invalid stop_signal: %w", c.Label, err)
+	}
+	c.stopSignal = sig
+	if c.StopTimeout.Duration <= 0 {
+		c.StopTimeout.Duration = defaultStopTimeout
+	}
+	c.forwardSignals = nil
+	for _, name := range c.ForwardSignals {
+		sig, err := parseSignal(name)
+		if err != nil {
+			return fmt.Errorf("[%s] invalid forward_signals: %w", c.Label, err)
+		}
+		if sig != syscall.SIGUSR1 && sig != syscall.SIGUSR2 {
+			return fmt.Errorf("[%s] invalid forward_signals: only SIGUSR1 and SIGUSR2 can be forwarded", c.Label)
+		}
+		c.forwardSignals = append(c.forwardSignals, sig)
+	}
+
+	if c.MaxRestarts < 0 {
+		return fmt.Errorf("[%s] max_restarts must not be negative", c.Label)
+	}
+	if c.Backoff.Duration <= 0 {
+		c.Backoff.Duration = defaultBackoff
+	}
+	if c.MaxBackoff.Duration <= 0 {
+		c.MaxBackoff.Duration = defaultMaxBackoff
+	}
+	if c.MaxBackoff.Duration < c.Backoff.Duration {
+		c.MaxBackoff.Duration = c.Backoff.Duration
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package rousego
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stateDir is the directory rousego keeps its runtime files in, relative to
+// the directory of the config file
+const stateDir = ".rousego"
+
+const socketName = "rousego.sock"
+
+// processStatus is the state of a process as reported by "rousego ps"
+type processStatus struct {
+	Label    string        `json:"label"`
+	State    string        `json:"state"`
+	Pid      int           `json:"pid,omitempty"`
+	Restarts int           `json:"restarts"`
+	Uptime   time.Duration `json:"uptime,omitempty"`
+	Ready    string        `json:"ready,omitempty"`
+	Health   string        `json:"health,omitempty"`
+	LastExit string        `json:"last_exit,omitempty"`
+	NextRun  time.Time     `json:"next_run,omitzero"`
+	Runs     int           `json:"runs,omitempty"`
+	// RSS is the resident memory of the process group in bytes, CPU its
+	// CPU usage in percent of one core
+	RSS int64   `json:"rss,omitempty"`
+	CPU float64 `json:"cpu,omitempty"`
+}
+
+func (p *process) status() processStatus {
+	up := p.uptime()
+	u := p.usage()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := processStatus{
+		Label:    p.Name,
+		State:    p.State,
+		Restarts: p.Restarts,
+		Uptime:   up,
+		Ready:    p.ReadyState,
+		Health:   p.Health,
+		RSS:      u.RSS,
+		CPU:      u.CPU,
+		LastExit: p.LastExit,
+		NextRun:  p.NextRun,
+		Runs:     p.Runs,
+	}
+	if p.Running && p.Cmd != nil && p.Cmd.Process != nil {
+		s.Pid = p.Cmd.Process.Pid
+	}
+	return s
+}
+
+// maxSocketPath is the longest path of a unix domain socket which works
+// everywhere, sun_path has 104 bytes on macOS and 108 on Linux
+const maxSocketPath = 103
+
+// socketPaths returns the paths the control socket for the config file in
+// dir can have. It is created next to the config file unless the path is
+// too long or the directory is not writable, then in the runtime directory
+// of the user, named after a hash of dir.
+func socketPaths(dir string) []string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	var paths []string
+	if path := filepath.Join(dir, stateDir, socketName); len(path) <= maxSocketPath {
+		paths = append(paths, path)
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(dir))
+	return append(paths, filepath.Join(runtimeDir, "rousego-"+hex.EncodeToString(sum[:8])+".sock"))
+}
+
+// socketPath returns the path of the control socket of the rousego running
+// the config file in dir, or the preferred path if there is no socket
+func socketPath(dir string) string {
+	paths := socketPaths(dir)
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return paths[0]
+}
+
+// startControlServer serves the control API on a unix domain socket for the
+// config file in dir. The returned function stops the server and removes the
+// socket. Without socket rousego still runs, only the client subcommands
+// cannot reach it.
+func startControlServer(dir string) (func(), error) {
+	for _, path := range socketPaths(dir) {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if _, err := newSocketClient(path).ps(); err == nil {
+			return nil, errors.New("rousego is already running for " + dir)
+		}
+		// left behind by a rousego which did not stop cleanly
+		os.Remove(path)
+	}
+
+	var (
+		listener net.Listener
+		path     string
+		errs     []string
+	)
+	for _, path = range socketPaths(dir) {
+		var err error
+		if listener, err = listenUnix(path); err == nil {
+			break
+		}
+		errs = append(errs, err.Error())
+	}
+	if listener == nil {
+		slog.Warn("Could not create control socket, ps, logs, start, stop and restart will not work: " + strings.Join(errs, "; "))
+		return func() {}, nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ps", handlePs)
+	mux.HandleFunc("POST /start/{label}", handleAction)
+	mux.HandleFunc("POST /stop/{label}", handleAction)
+	mux.HandleFunc("POST /restart/{label}", handleAction)
+	mux.HandleFunc("GET /logs", handleLogs)
+	mux.HandleFunc("GET /logs/{label}", handleLogs)
+
+	server := &http.Server{Handler: mux}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Control socket: " + fmt.Sprint(err))
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+		os.Remove(path)
+	}, nil
+}
+
+func listenUnix(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", path)
+}
+
+func findProcess(label string) *process {
+	for _, p := range allProcesses() {
+		if p.Name == label {
+			return p
+		}
+	}
+	return nil
+}
+
+func handlePs(w http.ResponseWriter, r *http.Request) {
+	var list []processStatus
+	for _, p := range allProcesses() {
+		list = append(list, p.status())
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func handleAction(w http.ResponseWriter, r *http.Request) {
+	label := r.PathValue("label")
+	p := findProcess(label)
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown process " + label})
+		return
+	}
+
+	action := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+	switch action {
+	case "start":
+		go p.start()
+	case "stop":
+		go p.shutdown()
+	case "restart":
+		go p.restart("(requested via control socket)")
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": action + " " + label})
+}
+
+// handleLogs writes the buffered lines as plain text. With ?follow=true new
+// lines are streamed until the client disconnects.
+func handleLogs(w http.ResponseWriter, r *http.Request) {
+	buffer := combinedLogs
+	label := r.PathValue("label")
+	if label != "" {
+		p := findProcess(label)
+		if p == nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown process " + label})
+			return
+		}
+		buffer = p.logs
+	}
+
+	lines, ch, stop := buffer.follow()
+	defer stop()
+
+	write := func(l logLine) {
+		if label == "" {
+			fmt.Fprintf(w, "[%s] %s\n", l.Name, l.Text)
+		} else {
+			fmt.Fprintln(w, l.Text)
+		}
+	}
+
+	tail, err := strconv.Atoi(r.URL.Query().Get("lines"))
+	if err != nil || tail < 0 || tail > len(lines) {
+		tail = len(lines)
+	}
+	for _, l := range lines[len(lines)-tail:] {
+		write(l)
+	}
+
+	if r.URL.Query().Get("follow") != "true" {
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case l := <-ch:
+			write(l)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,153 @@
+package rousego
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// findCycle returns the labels forming a dependency cycle, e.g.
+// [A B A], or nil if the dependency graph is acyclic
+func findCycle(cmds []cfgCommands) []string {
+	deps := make(map[string][]string)
+	for _, c := range cmds {
+		deps[c.Label] = c.DependsOn
+	}
+
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(label string) []string
+	visit = func(label string) []string {
+		switch state[label] {
+		case done:
+			return nil
+		case inProgress:
+			for i, l := range path {
+				if l == label {
+					return append(append([]string{}, path[i:]...), label)
+				}
+			}
+		}
+
+		state[label] = inProgress
+		path = append(path, label)
+		for _, dep := range deps[label] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[label] = done
+		return nil
+	}
+
+	for _, c := range cmds {
+		if cycle := visit(c.Label); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// linkDependencies wires up the dependency graph between the processes. It
+// replaces the links of a previous call.
+func linkDependencies(procs []*process) {
+	byLabel := make(map[string]*process)
+	for _, p := range procs {
+		byLabel[p.Name] = p
+	}
+	deps := make(map[*process][]*process)
+	dependents := make(map[*process][]*process)
+	for _, p := range procs {
+		for _, dep := range p.cfg.DependsOn {
+			d := byLabel[dep]
+			deps[p] = append(deps[p], d)
+			dependents[d] = append(dependents[d], p)
+		}
+	}
+	for _, p := range procs {
+		p.mu.Lock()
+		p.deps = deps[p]
+		p.dependents = dependents[p]
+		p.mu.Unlock()
+	}
+}
+
+// links returns the processes this one depends on and the processes
+// depending on this one
+func (p *process) links() (deps, dependents []*process) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.deps, p.dependents
+}
+
+// waitForDependencies blocks until all dependencies are ready. It returns
+// false if the process must not be started anymore.
+func (p *process) waitForDependencies() bool {
+	_, _, _, stop := p.signals()
+
+	deps, _ := p.links()
+	for _, d := range deps {
+		ready, failed, done, _ := d.signals()
+		if ready == nil {
+			slog.Error("Not starting: " + p.label() + " dependency " + d.label() + " was never started")
+			return false
+		}
+		select {
+		case <-ready:
+			continue
+		default:
+		}
+
+		slog.Info("Waiting: " + p.label() + " for " + d.label())
+		select {
+		case <-ready:
+		case <-done:
+			select {
+			case <-ready:
+			default:
+				if d.cfg.Type == typeOneshot {
+					slog.Error("Not starting: " + p.label() + " dependency " + d.label() + " did not finish successfully")
+				} else {
+					slog.Error("Not starting: " + p.label() + " dependency " + d.label() + " stopped before it was ready")
+				}
+				return false
+			}
+		case <-failed:
+			slog.Error("Not starting: " + p.label() + " dependency " + d.label() + " did not become ready")
+			return false
+		case <-stop:
+			return false
+		}
+	}
+	return true
+}
+
+// shutdownAll stops all processes in reverse dependency order: a process is
+// shut down once all processes depending on it have finished. It returns
+// when all processes have finished.
+func shutdownAll() {
+	var wg sync.WaitGroup
+	for _, p := range allProcesses() {
+		wg.Add(1)
+		go func(p *process) {
+			defer wg.Done()
+			_, dependents := p.links()
+			for _, d := range dependents {
+				if _, _, done, _ := d.signals(); done != nil {
+					<-done
+				}
+			}
+			p.shutdown()
+			if _, _, done, _ := p.signals(); done != nil {
+				<-done
+			}
+		}(p)
+	}
+	wg.Wait()
+}
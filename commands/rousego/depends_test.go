@@ -0,0 +1,32 @@
+package rousego
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindCycle(t *testing.T) {
+	cmd := func(label string, deps ...string) cfgCommands {
+		return cfgCommands{Label: label, DependsOn: deps}
+	}
+	tests := []struct {
+		name string
+		cmds []cfgCommands
+		want []string
+	}{
+		{"no dependencies", []cfgCommands{cmd("A"), cmd("B")}, nil},
+		{"chain", []cfgCommands{cmd("A", "B"), cmd("B", "C"), cmd("C")}, nil},
+		{"diamond", []cfgCommands{cmd("A", "B", "C"), cmd("B", "D"), cmd("C", "D"), cmd("D")}, nil},
+		{"self", []cfgCommands{cmd("A", "A")}, []string{"A", "A"}},
+		{"two", []cfgCommands{cmd("A", "B"), cmd("B", "A")}, []string{"A", "B", "A"}},
+		{"behind a chain", []cfgCommands{cmd("A", "B"), cmd("B", "C"), cmd("C", "D"), cmd("D", "B")}, []string{"B", "C", "D", "B"}},
+		{"unknown dependency", []cfgCommands{cmd("A", "X")}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findCycle(tt.cmds); !slices.Equal(got, tt.want) {
+				t.Errorf("findCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
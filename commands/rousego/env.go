@@ -0,0 +1,77 @@
+package rousego
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/subosito/gotenv"
+)
+
+var defaultShell = []string{"sh", "-c"}
+
+// command builds the exec.Cmd for the next start of the process
+func (p *process) command() (*exec.Cmd, error) {
+	argv := p.cfg.Args
+	if len(argv) == 0 {
+		argv = append(append([]string{}, p.cfg.Shell...), p.cfg.Cmd)
+	}
+
+	env, err := p.environ()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = p.cfg.Dir
+	cmd.Env = env
+	// an own process group allows to stop the command including all its children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd, nil
+}
+
+// environment returns the environment of rousego as map
+func environment() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// environ returns the environment of the process. Later sources win:
+// the environment of rousego, env_file, the top-level [env] table and
+// finally the env table of the command.
+func (p *process) environ() ([]string, error) {
+	env := environment()
+
+	if p.cfg.EnvFile != "" {
+		fileEnv, err := gotenv.Read(p.cfg.EnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read env_file: %w", err)
+		}
+		for k, v := range fileEnv {
+			env[k] = v
+		}
+	}
+
+	for k, v := range p.cfg.Env {
+		env[k] = v
+	}
+
+	return environList(env), nil
+}
+
+func environList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
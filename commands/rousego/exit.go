@@ -0,0 +1,127 @@
+package rousego
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	exitOnFailure   bool
+	exitOnFirstExit bool
+)
+
+// exitError is returned by run if a process failed. ExitCode is used as exit
+// status of rousego.
+type exitError struct {
+	code int
+	msg  string
+}
+
+func (e *exitError) Error() string {
+	return e.msg
+}
+
+func (e *exitError) ExitCode() int {
+	return e.code
+}
+
+// exitCode returns the exit code of a finished command. Commands killed by a
+// signal get 128+signal like in a shell, commands which could not be started 127.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	return 127
+}
+
+// exitCondition returns why the session has to end because of
+// --exit-on-failure or --exit-on-first-exit, or an empty string
+func exitCondition() string {
+	for _, p := range allProcesses() {
+		p.mu.Lock()
+		state, failed := p.State, p.Failed
+		p.mu.Unlock()
+
+		if state != stateExited {
+			continue
+		}
+		if exitOnFailure && failed {
+			return p.label() + " failed"
+		}
+		// oneshot commands are expected to exit
+		if exitOnFirstExit && p.cfg.Type != typeOneshot {
+			return p.label() + " exited"
+		}
+	}
+	return ""
+}
+
+// result returns the error run reports for the finished processes: nil if
+// all succeeded, otherwise an exitError with the exit code of the process
+// which failed first
+func result() error {
+	var (
+		failed []string
+		first  *process
+	)
+	for _, p := range allProcesses() {
+		p.mu.Lock()
+		switch {
+		case p.Failed:
+			failed = append(failed, p.Name)
+			if first == nil || p.FinishedAt.Before(first.FinishedAt) {
+				first = p
+			}
+		case p.State == stateSkipped:
+			failed = append(failed, p.Name)
+		}
+		p.mu.Unlock()
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	code := 1
+	if first != nil {
+		code = first.ExitCode
+	}
+	return &exitError{
+		code: code,
+		msg:  strconv.Itoa(len(failed)) + " of " + strconv.Itoa(len(allProcesses())) + " commands failed: " + strings.Join(failed, ", "),
+	}
+}
+
+// printSummary writes a table with the result of every process
+func printSummary(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LABEL\tRESULT\tEXIT\tDURATION\tRESTARTS")
+	for _, p := range allProcesses() {
+		p.mu.Lock()
+		result, code, runTime := "ok", strconv.Itoa(p.ExitCode), p.RunTime.Round(time.Millisecond).String()
+		switch {
+		case p.State == stateSkipped || p.FinishedAt.IsZero():
+			result, code, runTime = "not started", "-", "-"
+		case p.Failed:
+			result = "failed"
+		case p.State == stateStopped:
+			result = "stopped"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", p.Name, result, code, runTime, p.Restarts)
+		p.mu.Unlock()
+	}
+	tw.Flush()
+}
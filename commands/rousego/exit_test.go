@@ -0,0 +1,76 @@
+package rousego
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestExitCode(t *testing.T) {
+	run := func(args ...string) error {
+		return exec.Command(args[0], args[1:]...).Run()
+	}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, 0},
+		{"exit status", run("sh", "-c", "exit 3"), 3},
+		{"killed by signal", run("sh", "-c", "kill -TERM $$"), 143},
+		{"killed by SIGKILL", run("sh", "-c", "kill -KILL $$"), 137},
+		{"not started", run("rousego-does-not-exist"), 127},
+		{"other error", errors.New("boom"), 127},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	start := time.Now()
+	ok := func(name string) *process {
+		return &process{Name: name, State: stateExited}
+	}
+	failed := func(name string, code int, after time.Duration) *process {
+		return &process{Name: name, State: stateExited, Failed: true, ExitCode: code, FinishedAt: start.Add(after)}
+	}
+	tests := []struct {
+		name     string
+		procs    []*process
+		wantCode int
+		wantMsg  string
+	}{
+		{"all ok", []*process{ok("A"), ok("B")}, 0, ""},
+		{"stopped is ok", []*process{{Name: "A", State: stateStopped}}, 0, ""},
+		{"one failed", []*process{ok("A"), failed("B", 3, 0)}, 3, "1 of 2 commands failed: B"},
+		{"first failure wins", []*process{failed("A", 2, time.Second), failed("B", 5, 0)}, 5, "2 of 2 commands failed: A, B"},
+		{"skipped", []*process{ok("A"), {Name: "B", State: stateSkipped}}, 1, "1 of 2 commands failed: B"},
+		{"failed and skipped", []*process{failed("A", 4, 0), {Name: "B", State: stateSkipped}}, 4, "2 of 2 commands failed: A, B"},
+	}
+	t.Cleanup(func() { setProcesses(nil) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setProcesses(tt.procs)
+			err := result()
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("result() = %v, want nil", err)
+				}
+				return
+			}
+			var exitErr *exitError
+			if !errors.As(err, &exitErr) {
+				t.Fatalf("result() = %v, want an exitError", err)
+			}
+			if exitErr.ExitCode() != tt.wantCode || exitErr.Error() != tt.wantMsg {
+				t.Errorf("result() = %d %q, want %d %q", exitErr.ExitCode(), exitErr.Error(), tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
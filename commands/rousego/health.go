@@ -0,0 +1,228 @@
+package rousego
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	healthStarting  = "starting"
+	healthHealthy   = "healthy"
+	healthUnhealthy = "unhealthy"
+)
+
+const (
+	defaultHealthInterval  = 10 * time.Second
+	defaultHealthTimeout   = 5 * time.Second
+	defaultHealthThreshold = 3
+)
+
+// cfgHealth defines a health check which runs periodically while the
+// process is running. Exactly one of HTTP, TCP or Cmd has to be set.
+type cfgHealth struct {
+	// HTTP is a URL which has to answer with a 2xx status code
+	HTTP string `toml:"http,omitempty"`
+	// TCP is an address like "localhost:8080" which has to accept connections
+	TCP string `toml:"tcp,omitempty"`
+	// Cmd is run by the shell of the command and has to exit with 0
+	Cmd string `toml:"cmd,omitempty"`
+
+	Interval duration `toml:"interval,omitempty"`
+	Timeout  duration `toml:"timeout,omitempty"`
+	// Threshold is the number of failed checks in a row after which the
+	// process is restarted
+	Threshold int `toml:"threshold,omitempty"`
+}
+
+func (h *cfgHealth) validate(label string) error {
+	n := 0
+	for _, v := range []string{h.HTTP, h.TCP, h.Cmd} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("[%s] healthcheck needs exactly one of http, tcp or cmd", label)
+	}
+
+	if h.HTTP != "" {
+		if _, err := url.ParseRequestURI(h.HTTP); err != nil {
+			return fmt.Errorf("[%s] invalid healthcheck.http: %w", label, err)
+		}
+	}
+
+	if h.Interval.Duration <= 0 {
+		h.Interval.Duration = defaultHealthInterval
+	}
+	if h.Timeout.Duration <= 0 {
+		h.Timeout.Duration = defaultHealthTimeout
+	}
+	if h.Threshold < 0 {
+		return fmt.Errorf("[%s] healthcheck.threshold must not be negative", label)
+	}
+	if h.Threshold == 0 {
+		h.Threshold = defaultHealthThreshold
+	}
+	return nil
+}
+
+func (h *cfgHealth) String() string {
+	switch {
+	case h.HTTP != "":
+		return "http " + h.HTTP
+	case h.TCP != "":
+		return "tcp " + h.TCP
+	default:
+		return "cmd " + h.Cmd
+	}
+}
+
+// checkHealth runs the health check once. It returns nil if the process is healthy.
+func (p *process) checkHealth(ctx context.Context) error {
+	h := p.cfg.Healthcheck
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout.Duration)
+	defer cancel()
+
+	switch {
+	case h.HTTP != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.HTTP, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+
+	case h.TCP != "":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", h.TCP)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+
+	default:
+		shell := p.cfg.Shell
+		if len(shell) == 0 {
+			shell = defaultShell
+		}
+		env, err := p.environ()
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, shell[0], append(shell[1:], h.Cmd)...)
+		cmd.Dir = p.cfg.Dir
+		cmd.Env = env
+		// the timeout kills the check including its children
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Cancel = func() error {
+			return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		}
+		cmd.WaitDelay = time.Second
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("timeout after %s", h.Timeout)
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// healthcheck checks the process every interval once it is ready and
+// restarts it after threshold failed checks in a row. ctx is cancelled when
+// the process exits.
+func (p *process) healthcheck(ctx context.Context) {
+	h := p.cfg.Healthcheck
+	if h == nil {
+		return
+	}
+
+	ready, _, _, _ := p.signals()
+	select {
+	case <-ready:
+	case <-ctx.Done():
+		return
+	}
+	p.setHealth(healthStarting)
+
+	failures := 0
+	for {
+		select {
+		case <-time.After(h.Interval.Duration):
+		case <-ctx.Done():
+			p.setHealth("")
+			return
+		}
+
+		err := p.checkHealth(ctx)
+		if ctx.Err() != nil {
+			p.setHealth("")
+			return
+		}
+		if err == nil {
+			if p.setHealth(healthHealthy) != healthHealthy {
+				if !p.emit(eventHealthy, h.String()) {
+					slog.Info("Healthy: " + p.label() + " " + h.String())
+				}
+			}
+			failures = 0
+			continue
+		}
+
+		failures++
+		msg := h.String() + ": " + err.Error() + " (" + strconv.Itoa(failures) + "/" + strconv.Itoa(h.Threshold) + ")"
+		if failures < h.Threshold {
+			if !p.emit(eventHealthFailed, msg) {
+				slog.Warn("Health check failed: " + p.label() + " " + msg)
+			}
+			continue
+		}
+
+		p.setHealth(healthUnhealthy)
+		if !p.emit(eventUnhealthy, msg) {
+			slog.Error("Unhealthy: " + p.label() + " " + msg)
+		}
+		// unlike restarts requested by the user, an unhealthy process counts
+		// against max_restarts
+		p.mu.Lock()
+		gaveUp := p.cfg.MaxRestarts > 0 && p.policyRestarts >= p.cfg.MaxRestarts
+		if !gaveUp {
+			p.policyRestarts++
+		}
+		p.mu.Unlock()
+		if gaveUp {
+			if !p.emit(eventGaveUp, "unhealthy, reached max_restarts") {
+				slog.Error("Giving up: " + p.label() + " is unhealthy and reached max_restarts")
+			}
+			return
+		}
+		// restart terminates the current run, which cancels ctx
+		go p.restart("(unhealthy)")
+		return
+	}
+}
+
+// setHealth sets the health state and returns the previous one
+func (p *process) setHealth(health string) string {
+	p.mu.Lock()
+	previous := p.Health
+	p.Health = health
+	p.mu.Unlock()
+	return previous
+}
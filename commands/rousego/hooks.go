@@ -0,0 +1,42 @@
+package rousego
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	hookBefore = "before"
+	hookAfter  = "after"
+)
+
+// runHooks runs the before or after hooks one after another through the
+// normal process output. It stops at the first hook which fails.
+func runHooks(ctx context.Context, cfg *cfgMain, label string, hooks []string) error {
+	for _, hook := range hooks {
+		// like the commands, hooks run in the directory of the config file
+		c := cfgCommands{Label: label, Cmd: hook, Dir: cfg.dir, Env: cfg.Env}
+		if err := c.validate(); err != nil {
+			return err
+		}
+		p := newProcess(c)
+		p.start()
+
+		_, _, done, _ := p.signals()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			p.shutdown()
+			<-done
+			return fmt.Errorf("%s hook %q interrupted", label, hook)
+		}
+
+		p.mu.Lock()
+		failed, lastExit := p.Failed, p.LastExit
+		p.mu.Unlock()
+		if failed {
+			return fmt.Errorf("%s hook %q failed: %s", label, hook, lastExit)
+		}
+	}
+	return nil
+}
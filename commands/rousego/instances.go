@@ -0,0 +1,157 @@
+package rousego
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"text/template"
+)
+
+// instanceData is available as template data in cmd, args, env, dir and
+// log_file of commands with instances or port
+type instanceData struct {
+	// Instance counts from 1
+	Instance int
+	Port     int
+	// Label is the label of the instance, e.g. "Worker#2"
+	Label string
+}
+
+// templated reports whether the template variables are expanded for the
+// command. Other commands may contain "{{" for their own purposes.
+func (c *cfgCommands) templated() bool {
+	return c.Instances != 0 || c.Port != 0
+}
+
+// expandInstances replaces every command with instances > 1 by one command
+// per instance labeled "<label>#<n>" and expands the template variables.
+// Dependencies on a scaled command become dependencies on all its instances.
+func expandInstances(cmds []cfgCommands) ([]cfgCommands, error) {
+	instances := make(map[string][]string)
+	var expanded []cfgCommands
+
+	for _, c := range cmds {
+		if c.Instances < 0 {
+			return nil, fmt.Errorf("[%s] instances must not be negative", c.Label)
+		}
+		if c.Port < 0 || c.Port > 65535 {
+			return nil, fmt.Errorf("[%s] invalid port %d", c.Label, c.Port)
+		}
+		if !c.templated() {
+			expanded = append(expanded, c)
+			continue
+		}
+
+		n := max(c.Instances, 1)
+		for i := 1; i <= n; i++ {
+			inst := c
+			inst.base = c.Label
+			if n > 1 {
+				inst.Label = c.Label + "#" + strconv.Itoa(i)
+				instances[c.Label] = append(instances[c.Label], inst.Label)
+			}
+
+			data := instanceData{Instance: i, Label: inst.Label}
+			if c.Port != 0 {
+				data.Port = c.Port + i - 1
+			} else {
+				port, err := pickedPort(inst.Label)
+				if err != nil {
+					return nil, fmt.Errorf("[%s] could not find a free port: %w", inst.Label, err)
+				}
+				data.Port = port
+			}
+			if err := inst.expandTemplates(data); err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, inst)
+		}
+	}
+
+	for i := range expanded {
+		var deps []string
+		for _, dep := range expanded[i].DependsOn {
+			if labels, ok := instances[dep]; ok {
+				deps = append(deps, labels...)
+			} else {
+				deps = append(deps, dep)
+			}
+		}
+		expanded[i].DependsOn = deps
+	}
+	return expanded, nil
+}
+
+func (c *cfgCommands) expandTemplates(data instanceData) error {
+	expand := func(field, s string) (string, error) {
+		t, err := template.New(field).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return "", fmt.Errorf("[%s] invalid template in %s: %w", c.Label, field, err)
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return "", fmt.Errorf("[%s] invalid template in %s: %w", c.Label, field, err)
+		}
+		return b.String(), nil
+	}
+
+	var err error
+	if c.Cmd, err = expand("cmd", c.Cmd); err != nil {
+		return err
+	}
+	if c.Dir, err = expand("dir", c.Dir); err != nil {
+		return err
+	}
+	if c.LogFile, err = expand("log_file", c.LogFile); err != nil {
+		return err
+	}
+	c.Args = slices.Clone(c.Args)
+	for i, arg := range c.Args {
+		if c.Args[i], err = expand("args", arg); err != nil {
+			return err
+		}
+	}
+	c.Env = maps.Clone(c.Env)
+	for k, v := range c.Env {
+		if c.Env[k], err = expand("env."+k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	pickedPortsMu sync.Mutex
+	// pickedPorts keeps the free ports picked for instances by label, so
+	// they don't change when the config is reloaded
+	pickedPorts = make(map[string]int)
+)
+
+// pickedPort returns the port picked for the instance before or a new free port
+func pickedPort(label string) (int, error) {
+	pickedPortsMu.Lock()
+	defer pickedPortsMu.Unlock()
+	if port, ok := pickedPorts[label]; ok {
+		return port, nil
+	}
+	port, err := freePort()
+	if err != nil {
+		return 0, err
+	}
+	pickedPorts[label] = port
+	return port, nil
+}
+
+// freePort asks the kernel for a currently unused TCP port
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
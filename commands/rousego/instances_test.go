@@ -0,0 +1,74 @@
+package rousego
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandInstancesDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		cmds []cfgCommands
+		want map[string][]string
+	}{
+		{
+			name: "scaled dependency",
+			cmds: []cfgCommands{
+				{Label: "Worker", Instances: 3, Port: 9000},
+				{Label: "Proxy", DependsOn: []string{"Worker"}},
+			},
+			want: map[string][]string{
+				"Worker#1": nil,
+				"Worker#2": nil,
+				"Worker#3": nil,
+				"Proxy":    {"Worker#1", "Worker#2", "Worker#3"},
+			},
+		},
+		{
+			name: "single instance keeps its label",
+			cmds: []cfgCommands{
+				{Label: "Api", Port: 8080},
+				{Label: "Web", DependsOn: []string{"Api"}},
+			},
+			want: map[string][]string{
+				"Api": nil,
+				"Web": {"Api"},
+			},
+		},
+		{
+			name: "instances with dependencies",
+			cmds: []cfgCommands{
+				{Label: "Db"},
+				{Label: "Worker", Instances: 2, Port: 9000, DependsOn: []string{"Db"}},
+				{Label: "Proxy", DependsOn: []string{"Db", "Worker"}},
+			},
+			want: map[string][]string{
+				"Db":       nil,
+				"Worker#1": {"Db"},
+				"Worker#2": {"Db"},
+				"Proxy":    {"Db", "Worker#1", "Worker#2"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandInstances(tt.cmds)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d commands, want %d", len(got), len(tt.want))
+			}
+			for _, c := range got {
+				want, ok := tt.want[c.Label]
+				if !ok {
+					t.Errorf("unexpected command %q", c.Label)
+					continue
+				}
+				if !slices.Equal(c.DependsOn, want) {
+					t.Errorf("[%s] depends_on = %v, want %v", c.Label, c.DependsOn, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package rousego
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// Types of the JSON records
+const (
+	recordLine  = "line"
+	recordEvent = "event"
+	recordLog   = "log"
+)
+
+// Lifecycle events of a process in the JSON output
+const (
+	eventStarted    = "started"
+	eventReady      = "ready"
+	eventNotReady   = "not_ready"
+	eventExited     = "exited"
+	eventRestarting = "restarting"
+	eventGaveUp     = "gave_up"
+	eventStopping   = "stopping"
+	eventKilled     = "killed"
+	eventSkipped    = "skipped"
+	// eventHealthFailed is a failed health check below the threshold
+	eventHealthFailed = "health_failed"
+	eventHealthy      = "healthy"
+	eventUnhealthy    = "unhealthy"
+)
+
+// jsonRecord is one line of the JSON output, either a line written by a
+// process or a lifecycle event
+type jsonRecord struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Event    string    `json:"event,omitempty"`
+	Label    string    `json:"label"`
+	Stream   string    `json:"stream,omitempty"`
+	Pid      int       `json:"pid,omitempty"`
+	Restart  int       `json:"restart"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// jsonWriter serializes all writes to stdout, so records don't interleave
+var jsonWriter = &lockedWriter{w: os.Stdout}
+
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
+
+func jsonMode() bool {
+	return output.Format == formatJSON
+}
+
+// startJSONOutput switches rousego's own logging to JSON records on stdout.
+// Lifecycle events replace the info messages, warnings and errors are kept
+// as records of type "log". The returned function restores the logger.
+func startJSONOutput() func() {
+	lipgloss.SetColorProfile(termenv.Ascii)
+
+	previous := slog.Default()
+	handler := slog.NewJSONHandler(jsonWriter, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.MessageKey {
+				a.Key = "message"
+			}
+			return a
+		},
+	})
+	slog.SetDefault(slog.New(handler).With("type", recordLog))
+	return func() {
+		slog.SetDefault(previous)
+	}
+}
+
+func writeJSONRecord(r jsonRecord) {
+	r.Message = ansi.Strip(r.Message)
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	jsonWriter.Write(append(b, '\n'))
+}
+
+// record returns a JSON record of the process with the current pid and
+// restart generation
+func (p *process) record(typ string, message string) jsonRecord {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	r := jsonRecord{
+		Time:    time.Now(),
+		Type:    typ,
+		Label:   p.Name,
+		Restart: p.Restarts,
+		Message: message,
+	}
+	if p.Cmd != nil && p.Cmd.Process != nil {
+		r.Pid = p.Cmd.Process.Pid
+	}
+	return r
+}
+
+// emit writes a lifecycle event in JSON mode. It reports whether the event
+// was written, callers log the human-oriented message otherwise.
+func (p *process) emit(event string, message string) bool {
+	if !jsonMode() {
+		return false
+	}
+	r := p.record(recordEvent, message)
+	r.Event = event
+	writeJSONRecord(r)
+	return true
+}
+
+// emitExit writes the exited event with the exit code of the last run
+func (p *process) emitExit(err error) bool {
+	if !jsonMode() {
+		return false
+	}
+	r := p.record(recordEvent, exitStatus(err))
+	r.Event = eventExited
+	code := exitCode(err)
+	r.ExitCode = &code
+	writeJSONRecord(r)
+	return true
+}
@@ -0,0 +1,190 @@
+package rousego
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// cfgLimits restricts the resources of a command
+type cfgLimits struct {
+	// Memory limits the memory of the whole process tree if a cgroup v2 can
+	// be created, otherwise the address space of every single process
+	Memory byteSize `toml:"memory,omitempty"`
+	// CPU limits the CPU time of every process
+	CPU duration `toml:"cpu,omitempty"`
+	// OpenFiles limits the number of open files of every process
+	OpenFiles int `toml:"open_files,omitempty"`
+}
+
+func (l *cfgLimits) validate(label string) error {
+	if l.Memory < 0 || l.CPU.Duration < 0 || l.OpenFiles < 0 {
+		return fmt.Errorf("[%s] limits must not be negative", label)
+	}
+	return nil
+}
+
+// supervisorCgroup is the leaf cgroup rousego moves itself into, so the
+// memory controller can be enabled in its own cgroup
+const supervisorCgroup = "supervisor"
+
+// cgroupRoot is the cgroup v2 directory of rousego below which a cgroup is
+// created for every command with a memory limit. It is empty if there is
+// no cgroup v2, it is not delegated to the user or it contains other
+// processes than rousego.
+//
+// A cgroup with processes cannot enable controllers for its children, so
+// rousego first moves itself into the child cgroup "supervisor". The cgroups
+// of the commands are created next to it. This has to happen before
+// rousego starts any process, see setupCgroups.
+var cgroupRoot = sync.OnceValue(func() string {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return ""
+	}
+	// with cgroup v2 only there is exactly one line "0::/path"
+	path, ok := strings.CutPrefix(strings.TrimSpace(string(b)), "0::")
+	if !ok || strings.Contains(path, "\n") {
+		return ""
+	}
+	dir := filepath.Join("/sys/fs/cgroup", path)
+
+	control := filepath.Join(dir, "cgroup.subtree_control")
+	enabled, err := os.ReadFile(control)
+	if err != nil {
+		return ""
+	}
+	if slices.Contains(strings.Fields(string(enabled)), "memory") {
+		// only the root cgroup may have processes and enabled controllers
+		return dir
+	}
+
+	// other processes, e.g. the shell of a terminal session, are not moved
+	procs, err := os.ReadFile(filepath.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return ""
+	}
+	pid := strconv.Itoa(os.Getpid())
+	if fields := strings.Fields(string(procs)); len(fields) != 1 || fields[0] != pid {
+		return ""
+	}
+
+	leaf := filepath.Join(dir, supervisorCgroup)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return ""
+	}
+	if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(pid), 0); err != nil {
+		os.Remove(leaf)
+		return ""
+	}
+	// fails if rousego's cgroup is not delegated
+	if err := os.WriteFile(control, []byte("+memory"), 0); err != nil {
+		os.WriteFile(filepath.Join(dir, "cgroup.procs"), []byte(pid), 0)
+		os.Remove(leaf)
+		return ""
+	}
+	return dir
+})
+
+// setupCgroups prepares the cgroups for memory limits before any process is
+// started. A memory limit added later by a reload may fall back to rlimits.
+func setupCgroups(cmds []cfgCommands) {
+	for _, c := range cmds {
+		if c.Limits != nil && c.Limits.Memory > 0 {
+			cgroupRoot()
+			return
+		}
+	}
+}
+
+// cgroupWarning is logged once if memory limits fall back to rlimits
+var cgroupWarning sync.Once
+
+// cgroup creates a cgroup with the memory limit for the next run and lets
+// cmd start in it. It returns the directory of the cgroup, or "" if the
+// memory limit has to be applied as rlimit, and the opened directory which
+// has to be closed once cmd has been started.
+func (p *process) cgroup(cmd *exec.Cmd) (string, *os.File) {
+	l := p.cfg.Limits
+	if l == nil || l.Memory == 0 {
+		return "", nil
+	}
+
+	root := cgroupRoot()
+	if root == "" {
+		cgroupWarning.Do(func() {
+			slog.Warn("No cgroup v2 available, memory limits apply to every single process")
+		})
+		return "", nil
+	}
+	dir := filepath.Join(root, "rousego-"+strconv.Itoa(os.Getpid())+"-"+unsafeFileChars.ReplaceAllString(p.Name, "_"))
+	if err := os.Mkdir(dir, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		slog.Warn("Could not create cgroup for " + p.label() + ": " + err.Error())
+		return "", nil
+	}
+	if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.FormatInt(int64(l.Memory), 10)), 0); err != nil {
+		slog.Warn("Could not set memory limit of " + p.label() + ": " + err.Error())
+		os.Remove(dir)
+		return "", nil
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		os.Remove(dir)
+		return "", nil
+	}
+	// the child is started in the cgroup, so there is no moment without limit
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(f.Fd())
+	return dir, f
+}
+
+// limitsShell is the shell which sets the rlimits before it runs the command
+const limitsShell = "/bin/sh"
+
+// setLimits applies the limits which are not covered by the cgroup as
+// rlimits to cmd. They have to be set between fork and exec, otherwise the
+// command could start processes without limits. So cmd starts a shell, which
+// sets them with ulimit and then replaces itself by the command.
+func (p *process) setLimits(cmd *exec.Cmd, cgroup string) {
+	l := p.cfg.Limits
+	if l == nil || cmd.Err != nil {
+		return
+	}
+	var ulimits []string
+	if l.Memory > 0 && cgroup == "" {
+		// ulimit -v counts KB
+		ulimits = append(ulimits, "ulimit -v "+strconv.FormatInt(max(int64(l.Memory)>>10, 1), 10))
+	}
+	if l.CPU.Duration > 0 {
+		// RLIMIT_CPU counts whole seconds
+		ulimits = append(ulimits, "ulimit -t "+strconv.FormatFloat(max(l.CPU.Duration.Seconds(), 1), 'f', 0, 64))
+	}
+	if l.OpenFiles > 0 {
+		ulimits = append(ulimits, "ulimit -n "+strconv.Itoa(l.OpenFiles))
+	}
+	if len(ulimits) == 0 {
+		return
+	}
+
+	// $0 names the shell in its error messages, "$@" is the command
+	script := strings.Join(ulimits, " && ") + ` && exec "$@"`
+	cmd.Args = append([]string{limitsShell, "-c", script, "rousego-limits", cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = limitsShell
+}
+
+// removeCgroup removes the cgroup of a finished run
+func removeCgroup(dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.Remove(dir); err != nil {
+		slog.Warn("Could not remove cgroup " + dir + ": " + err.Error())
+	}
+}
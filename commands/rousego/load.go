@@ -0,0 +1,227 @@
+package rousego
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// configNames are the file names rousego looks for, in this order. A
+// Procfile is run directly if there is no rousego config next to it.
+var configNames = []string{"rousego.toml", "rousego.yaml", "rousego.yml", "rousego.json", procfileName}
+
+// findConfig returns the path of the config file. Without an explicit path
+// the current directory and all its parents are searched, like git does.
+func findConfig(path string) (string, error) {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("config file %s not found", path)
+		}
+		return filepath.Abs(path)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range configNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no rousego.toml or Procfile found in the current directory or any parent directory")
+		}
+		dir = parent
+	}
+}
+
+// loadConfig reads, validates and resolves the config file at path
+func loadConfig(path string) (*cfgMain, error) {
+	cfg, err := readConfig(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg.interpolate()
+	cfg.resolvePaths()
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readConfig decodes the config file at path and merges the files it
+// includes. including holds the files which include this one.
+func readConfig(path string, including []string) (*cfgMain, error) {
+	raw, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg cfgMain
+	if err := decodeConfig(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", filepath.Base(path), err)
+	}
+	cfg.file = path
+	cfg.dir = filepath.Dir(path)
+	for i := range cfg.Cmds {
+		cfg.Cmds[i].configDir = cfg.dir
+	}
+
+	including = append(including, path)
+	for _, name := range cfg.Include {
+		included := resolvePath(cfg.dir, name)
+		if slices.Contains(including, included) {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(including, included), " -> "))
+		}
+		inc, err := readConfig(included, including)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", name, err)
+		}
+		cfg.merge(inc)
+	}
+	cfg.Include = nil
+	return &cfg, nil
+}
+
+// merge adds an included config. Commands and hooks of inc come first,
+// env, vars and profiles of cfg win.
+func (cfg *cfgMain) merge(inc *cfgMain) {
+	cfg.Cmds = append(inc.Cmds, cfg.Cmds...)
+	cfg.Before = append(inc.Before, cfg.Before...)
+	cfg.After = append(inc.After, cfg.After...)
+
+	cfg.Env = mergeMap(inc.Env, cfg.Env)
+	cfg.Vars = mergeMap(inc.Vars, cfg.Vars)
+	cfg.Profiles = mergeMap(inc.Profiles, cfg.Profiles)
+
+	// [output] and [logging] are taken from the including file if it has them
+	if cfg.Output == (cfgOutput{}) {
+		cfg.Output = inc.Output
+	}
+	if cfg.Logging == nil {
+		cfg.Logging = inc.Logging
+	}
+}
+
+// mergeMap returns the entries of both maps, entries of m win
+func mergeMap[V any](base, m map[string]V) map[string]V {
+	if len(base) == 0 {
+		return m
+	}
+	merged := maps.Clone(base)
+	maps.Copy(merged, m)
+	return merged
+}
+
+// readConfigFile parses a TOML, YAML or JSON file or a Procfile into a generic map
+func readConfigFile(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", path, err)
+	}
+
+	if isProcfile(path) {
+		return procfileConfig(path, b)
+	}
+
+	raw := make(map[string]any)
+	name := filepath.Base(path)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", name, err)
+		}
+	case ".json":
+		if err := json.Unmarshal(b, &raw); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				line := bytes.Count(b[:syntaxErr.Offset], []byte("\n")) + 1
+				return nil, fmt.Errorf("could not parse %s line %d: %w", name, line, err)
+			}
+			return nil, fmt.Errorf("could not parse %s: %w", name, err)
+		}
+	default:
+		if err := toml.Unmarshal(b, &raw); err != nil {
+			var decodeErr *toml.DecodeError
+			if errors.As(err, &decodeErr) {
+				row, col := decodeErr.Position()
+				return nil, fmt.Errorf("could not parse %s line %d, column %d: %s", name, row, col, strings.TrimPrefix(err.Error(), "toml: "))
+			}
+			return nil, fmt.Errorf("could not parse %s: %w", name, err)
+		}
+	}
+	return raw, nil
+}
+
+// decodeConfig maps the generic config onto cfgMain. All formats share the
+// toml struct tags.
+func decodeConfig(raw map[string]any, cfg *cfgMain) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName:     "toml",
+		ErrorUnused: true,
+		DecodeHook:  mapstructure.TextUnmarshallerHookFunc(),
+		Result:      cfg,
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(raw)
+}
+
+// resolvePaths makes relative paths relative to the directory of the config file
+func (cfg *cfgMain) resolvePaths() {
+	if cfg.Logging != nil {
+		if cfg.Logging.Dir == "" {
+			cfg.Logging.Dir = filepath.Join(stateDir, "logs")
+		}
+		cfg.Logging.Dir = resolvePath(cfg.dir, cfg.Logging.Dir)
+	}
+	for i := range cfg.Cmds {
+		c := &cfg.Cmds[i]
+		// commands of included files are relative to their own file
+		dir := c.configDir
+		if dir == "" {
+			dir = cfg.dir
+		}
+		// commands run in the directory of the config file by default, not in
+		// the directory rousego was started from
+		if c.Dir == "" {
+			c.Dir = dir
+		}
+		c.Dir = resolvePath(dir, c.Dir)
+		c.EnvFile = resolvePath(dir, c.EnvFile)
+		c.LogFile = resolvePath(dir, c.LogFile)
+		if c.Ready != nil {
+			c.Ready.File = resolvePath(c.Dir, c.Ready.File)
+		}
+		if c.Watch != nil {
+			c.watchBase = c.Dir
+			for j, path := range c.Watch.Paths {
+				c.Watch.Paths[j] = resolvePath(c.watchBase, path)
+			}
+		}
+	}
+}
+
+func resolvePath(dir string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
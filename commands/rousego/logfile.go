@@ -0,0 +1,239 @@
+package rousego
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogMaxSize  = 10 << 20
+	defaultLogMaxFiles = 3
+	combinedLogName    = "combined"
+)
+
+// cfgLogging writes the output of the processes to files next to the
+// terminal output
+type cfgLogging struct {
+	// Dir contains one <label>.log per process (default ".rousego/logs")
+	Dir string `toml:"dir,omitempty"`
+	// Combined additionally writes the output of all processes to combined.log
+	Combined bool `toml:"combined,omitempty"`
+	// MaxSize is the size after which a log file is rotated
+	MaxSize byteSize `toml:"max_size,omitempty"`
+	// MaxFiles is the number of rotated files which are kept
+	MaxFiles int `toml:"max_files,omitempty"`
+}
+
+func (l *cfgLogging) validate() error {
+	if l.MaxSize <= 0 {
+		l.MaxSize = defaultLogMaxSize
+	}
+	if l.MaxFiles < 0 {
+		return fmt.Errorf("logging.max_files must not be negative")
+	}
+	if l.MaxFiles == 0 {
+		l.MaxFiles = defaultLogMaxFiles
+	}
+	return nil
+}
+
+// byteSize allows sizes like "10MB" or "512KB" in rousego.toml
+type byteSize int64
+
+var byteSizePattern = regexp.MustCompile(`^\s*(\d+)\s*(?:([KMG])I?)?B?\s*$`)
+
+func (s *byteSize) UnmarshalText(b []byte) error {
+	m := byteSizePattern.FindStringSubmatch(strings.ToUpper(string(b)))
+	if m == nil {
+		return fmt.Errorf("invalid size %q (expected e.g. \"10MB\")", string(b))
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	switch m[2] {
+	case "K":
+		n <<= 10
+	case "M":
+		n <<= 20
+	case "G":
+		n <<= 30
+	}
+	*s = byteSize(n)
+	return nil
+}
+
+func (s byteSize) MarshalText() ([]byte, error) {
+	switch {
+	case s >= 1<<30 && s%(1<<30) == 0:
+		return []byte(strconv.FormatInt(int64(s>>30), 10) + "GB"), nil
+	case s >= 1<<20 && s%(1<<20) == 0:
+		return []byte(strconv.FormatInt(int64(s>>20), 10) + "MB"), nil
+	case s >= 1<<10 && s%(1<<10) == 0:
+		return []byte(strconv.FormatInt(int64(s>>10), 10) + "KB"), nil
+	}
+	return []byte(strconv.FormatInt(int64(s), 10)), nil
+}
+
+// logFile is a log file which is rotated when it gets larger than maxSize.
+// Rotated files get the suffixes .1 (newest) to .<maxFiles>.
+type logFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	f        *os.File
+	size     int64
+}
+
+func openLogFile(path string, maxSize int64, maxFiles int) (*logFile, error) {
+	l := &logFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *logFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f = f
+	l.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file to .1 and drops the oldest rotated file
+func (l *logFile) rotate() error {
+	l.f.Close()
+	os.Remove(l.path + "." + strconv.Itoa(l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(l.path+"."+strconv.Itoa(i), l.path+"."+strconv.Itoa(i+1))
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// writeLine appends one line with time and stream. Errors are ignored, the
+// terminal output matters more than the log file.
+func (l *logFile) writeLine(t time.Time, stream, text string) {
+	if l == nil {
+		return
+	}
+	line := t.Format("2006-01-02T15:04:05.000Z07:00") + " " + stream + " " + text + "\n"
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return
+	}
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			l.f = nil
+			return
+		}
+	}
+	n, _ := l.f.WriteString(line)
+	l.size += int64(n)
+}
+
+func (l *logFile) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// combinedLogFile receives the output of all processes if logging.combined is set
+var combinedLogFile *logFile
+
+// unsafeFileChars are replaced in labels to get a file name
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._#@+-]+`)
+
+// openLogFiles opens the log files of all processes. The returned function
+// closes them.
+func openLogFiles(cfg *cfgMain) (func(), error) {
+	// includes the log files of processes added by a reload
+	closeAll := func() {
+		for _, p := range allProcesses() {
+			p.logFile.Close()
+		}
+		combinedLogFile.Close()
+	}
+
+	for _, p := range allProcesses() {
+		if err := p.openLog(cfg); err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+
+	if cfg.Logging != nil && cfg.Logging.Combined {
+		f, err := openLogFile(filepath.Join(cfg.Logging.Dir, combinedLogName+".log"), int64(cfg.Logging.MaxSize), cfg.Logging.MaxFiles)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("could not open combined log file: %w", err)
+		}
+		combinedLogFile = f
+	}
+	return closeAll, nil
+}
+
+// openLog opens the log file of the process if it has one
+func (p *process) openLog(cfg *cfgMain) error {
+	logging := cfg.Logging
+	if logging == nil {
+		// log_file of a command works without [logging] table
+		logging = &cfgLogging{}
+		logging.validate()
+	}
+
+	path := p.cfg.LogFile
+	if path == "" {
+		if cfg.Logging == nil {
+			return nil
+		}
+		path = filepath.Join(logging.Dir, unsafeFileChars.ReplaceAllString(p.Name, "_")+".log")
+	}
+	f, err := openLogFile(path, int64(logging.MaxSize), logging.MaxFiles)
+	if err != nil {
+		return fmt.Errorf("could not open log file of %s: %w", p.label(), err)
+	}
+	p.logFile = f
+	return nil
+}
+
+// writeLog writes a line of the process to its log file and the combined log file
+func (p *process) writeLog(l logLine) {
+	p.logFile.writeLine(l.Time, l.Stream, l.Text)
+	combinedLogFile.writeLine(l.Time, l.Stream, "["+p.Name+"] "+l.Text)
+}
+
+// logMarker notes lifecycle changes like starts and exits in the log files
+func (p *process) logMarker(text string) {
+	p.writeLog(logLine{Time: time.Now(), Name: p.Name, Stream: "rousego", Text: "--- " + text})
+}
@@ -0,0 +1,81 @@
+package rousego
+
+import "testing"
+
+func TestByteSizeUnmarshalText(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    byteSize
+		wantErr bool
+	}{
+		{s: "0", want: 0},
+		{s: "512", want: 512},
+		{s: "512B", want: 512},
+		{s: "10KB", want: 10 << 10},
+		{s: "10K", want: 10 << 10},
+		{s: "10KiB", want: 10 << 10},
+		{s: "10MB", want: 10 << 20},
+		{s: "10mb", want: 10 << 20},
+		{s: " 2 GB ", want: 2 << 30},
+		{s: "", wantErr: true},
+		{s: "MB", wantErr: true},
+		{s: "-1MB", wantErr: true},
+		{s: "1.5MB", wantErr: true},
+		{s: "10TB", wantErr: true},
+		{s: "10I", wantErr: true},
+		{s: "10IB", wantErr: true},
+		{s: "5I", wantErr: true},
+		{s: "10KIB", want: 10 << 10},
+		{s: "1Gi", want: 1 << 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			var got byteSize
+			err := got.UnmarshalText([]byte(tt.s))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("UnmarshalText(%q) = %d, want an error", tt.s, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("UnmarshalText(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByteSizeMarshalText(t *testing.T) {
+	tests := []struct {
+		s    byteSize
+		want string
+	}{
+		{0, "0"},
+		{512, "512"},
+		{1 << 10, "1KB"},
+		{1536, "1536"},
+		{10 << 20, "10MB"},
+		{1536 << 10, "1536KB"},
+		{2 << 30, "2GB"},
+		{(2 << 30) + (1 << 20), "2049MB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			b, err := tt.s.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("MarshalText(%d) = %q, want %q", tt.s, b, tt.want)
+			}
+			// the text reads back as the same size
+			var back byteSize
+			if err := back.UnmarshalText(b); err != nil || back != tt.s {
+				t.Errorf("UnmarshalText(%q) = %d, %v, want %d", b, back, err, tt.s)
+			}
+		})
+	}
+}
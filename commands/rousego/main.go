@@ -1,27 +1,23 @@
 package rousego
 
 import (
-	"bufio"
 	"context"
 	"errors"
-	"fmt"
-	"io"
-	"log"
 	"log/slog"
 	"os"
-	"os/exec"
-	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
-	"github.com/charmbracelet/lipgloss"
 	"github.com/korpa/y-cct/global/signalhandler"
-	"github.com/pelletier/go-toml/v2"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
-var vipercfg *viper.Viper
+var (
+	configFile string
+	tuiMode    bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var Cmd = &cobra.Command{
@@ -30,7 +26,10 @@ var Cmd = &cobra.Command{
 	Long: `rousego startes multiple processes in parallel
 
 
-Processes have to be defined in rousego.toml
+Processes have to be defined in rousego.toml. rousego looks for it in the
+current directory and all parent directories. rousego.yaml, rousego.yml and
+rousego.json with the same structure work as well. Without any of them a
+Procfile is run directly, "rousego import procfile" converts it.
 
 Example rousego.toml
 ----------------------------------------
@@ -43,195 +42,290 @@ cmd = "sleep 3 ; echo 'hello from backend via stdout'; sleep 4"
 [[cmds]]
 label = "Frontend"
 cmd = "sleep 2 ; echo 'hello from frontend via stdout' ; sleep 1"
+depends_on = ["Backend2"]
 
 [[cmds]]
 label = "Frontend2"
 cmd = "sleep 1 ; echo 'hello from frontend2 via stderr' 1>&2 ; sleep 1"
+restart = "on-failure"
+max_restarts = 3
 
 ----------------------------------------
 
+Top-level before and after lists contain commands which run one after another
+before the first and after the last process.
+
+include = ["../shared/rousego.db.toml"] adds the commands of other config
+files. cmd, args, dir and env values can use ${name} from a top-level [vars]
+table, the env of the command or the environment, ${name:-default} with a
+default. "rousego config print" shows the resolved configuration.
+
+A top-level [env] table applies to all commands. A top-level [output] table
+sets align = true, timestamps = "wall" or "relative" and mark_stderr = true,
+like the flags --align, --timestamps and --mark-stderr. --output json prints
+every line and lifecycle event as a JSON object. A top-level [logging] table
+writes the output of every command to .rousego/logs/<label>.log with dir,
+combined = true, max_size (default "10MB") and max_files (default 3).
+
+Options per command
+  type          "service" (default) or "oneshot" for setup tasks. Dependents of
+                a oneshot wait until it exited successfully
+  cmd           command line which is run by shell
+  args          argv which is executed directly without shell, instead of cmd
+  shell         shell used for cmd (default ["sh", "-c"]), e.g. ["bash", "-lc"]
+  dir           working directory (default: the directory of the config file)
+  env           environment variables, e.g. env = { PORT = "8080" }
+  env_file      file with KEY=value lines which are added to the environment
+  instances     number of instances, labeled "<label>#1", "<label>#2", ...
+  port          port of the first instance, the next instances get the next
+                ports (default: a free port). With instances or port, cmd, args,
+                env, dir and log_file can use {{.Instance}}, {{.Port}} and {{.Label}}
+  groups        group names for --only and --except, e.g. ["web"]
+  depends_on    labels of commands which have to be started first. On shutdown
+                a command is stopped after all commands depending on it
+  ready         readiness probe, one of tcp = "host:port", http = "URL",
+                log = "regexp" or file = "path", plus timeout (default "60s")
+                and interval (default "250ms"). Dependent commands wait for it
+  healthcheck   periodic health check once ready, one of http = "URL",
+                tcp = "host:port" or cmd = "command", plus interval (default "10s"),
+                timeout (default "5s") and threshold (default 3). Unhealthy
+                commands are restarted
+  limits        resource limits, e.g. limits = { memory = "2GB", cpu = "30m",
+                open_files = 4096 }. The memory limit uses a cgroup v2 if possible
+  schedule      run periodically on a cron schedule, e.g. "*/5 * * * *"
+  every         run periodically in an interval, e.g. "30s"
+  restart       "no" (default), "on-failure" or "always"
+  max_restarts  maximum number of restarts in a row by the restart policy, 0 (default) means unlimited
+  backoff       delay before the first restart (default "1s"), doubled on every restart
+  max_backoff   upper limit for the restart delay (default "30s")
+  log_file      log file of the command, also without [logging] table
+  pty           run the command in a pseudo-terminal, so it keeps colors and
+                progress output (Linux only)
+  color         color of the label, e.g. "#ff8800" (default derived from the label)
+  stop_signal   signal sent to the process group on shutdown (default "SIGTERM")
+  stop_timeout  time to wait before the process group is killed (default "10s")
+  forward_signals
+                signals rousego passes on to the process group, e.g. ["SIGUSR1"]
+  watch         restart on file changes, e.g. watch = { paths = ["./internal"],
+                include = ["*.go"], exclude = ["*_test.go"], debounce = "300ms" }
+
+--only and --except select commands by label or group, [profiles.<name>]
+tables with only and except lists are started with --profile <name>.
+Dependencies of selected commands are always started.
+
+At the end rousego prints a summary and exits with the exit code of the first
+failed command. --exit-on-failure stops all processes as soon as one fails,
+--exit-on-first-exit as soon as one exits.
+
+SIGHUP reloads the config file: added commands are started, removed ones are
+stopped and changed ones are restarted. SIGUSR1 and SIGUSR2 are forwarded to
+the commands listing them in forward_signals.
+
+--stdin <label> forwards the input of rousego to one process, in the terminal
+UI the i key sends typed lines to the selected process.
+
+While rousego is running, "rousego ps", "rousego start|stop|restart <label>"
+and "rousego logs [label] --follow" control it from another terminal through
+the socket .rousego/rousego.sock next to the config file.
+
 `,
 	// BashCompletionFunction: bashCompletionFunc,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := run(); err != nil {
+		if err := run(cmd); err != nil {
 			return err
 		}
 		return nil
 	},
 }
 
-var colors []string
-
-func init() {
-	colors = append(colors, "#BB00BB")
-	colors = append(colors, "#00BBBB")
-	colors = append(colors, "#BBBB00")
-	colors = append(colors, "#00BB00")
-}
-
-var processes []*process
+var (
+	processesMu sync.Mutex
+	processes   []*process
+)
 
-type process struct {
-	Name    string
-	Cmd     *exec.Cmd
-	Running bool
-	Command string
-	Style   lipgloss.Style
+// allProcesses returns the current processes. A reload replaces the slice
+// instead of modifying it, so it can be used without holding the lock.
+func allProcesses() []*process {
+	processesMu.Lock()
+	defer processesMu.Unlock()
+	return processes
 }
 
-type cfgMain struct {
-	Cmds []cfgCommands `toml:"cmds"`
+func setProcesses(procs []*process) {
+	processesMu.Lock()
+	processes = procs
+	processesMu.Unlock()
 }
 
-type cfgCommands struct {
-	Label string `toml:"label"`
-	Cmd   string `toml:"cmd"`
+func init() {
+	Cmd.Flags().StringSliceVar(&onlyFlag, "only", nil, "start only these commands or groups (plus their dependencies)")
+	Cmd.Flags().StringSliceVar(&exceptFlag, "except", nil, "do not start these commands or groups")
+	Cmd.Flags().StringVarP(&profileFlag, "profile", "p", "", "start the commands of a profile defined in [profiles.<name>]")
+	Cmd.Flags().BoolVar(&exitOnFailure, "exit-on-failure", false, "stop all processes as soon as one fails")
+	Cmd.Flags().BoolVar(&exitOnFirstExit, "exit-on-first-exit", false, "stop all processes as soon as one exits")
+	Cmd.Flags().Bool("align", false, "pad all labels to the same width")
+	Cmd.Flags().String("timestamps", timestampsNone, `prefix lines with a timestamp: "none", "wall" or "relative"`)
+	Cmd.Flags().Bool("mark-stderr", false, "mark lines written to stderr")
+	Cmd.Flags().StringP("output", "o", formatText, `output format: "text" or "json" for one JSON object per line`)
+	Cmd.Flags().StringVar(&stdinLabel, "stdin", "", "forward the input of rousego to this process")
+	Cmd.Flags().BoolVar(&tuiMode, "tui", false, "interactive terminal UI with one log pane per process")
+	Cmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "path to the config file (default: rousego.toml, .yaml, .yml or .json in the current or a parent directory)")
 }
 
-func run() error {
-	file, err := os.Open("rousego.toml")
+func run(cmd *cobra.Command) error {
+	path, err := findConfig(configFile)
 	if err != nil {
-		return errors.New("no rousego.toml found")
+		return err
 	}
-	defer file.Close()
-
-	var cfg cfgMain
-
-	b, err := io.ReadAll(file)
+	cfg, err := loadConfig(path)
 	if err != nil {
-		if err != nil {
-			return errors.New("could not read rousego.toml")
+		return err
+	}
+	if err := applyOutputFlags(cmd.Flags(), &cfg.Output); err != nil {
+		return err
+	}
+	output = cfg.Output
+	if jsonMode() {
+		if tuiMode {
+			return errors.New("--output json cannot be used together with --tui")
 		}
+		defer startJSONOutput()()
 	}
+	slog.Info("Using " + path)
 
-	err = toml.Unmarshal(b, &cfg)
+	closeControl, err := startControlServer(cfg.dir)
+	if err != nil {
+		return err
+	}
+	defer closeControl()
+
+	ctx, stopSignals := signalhandler.Init(context.Background(), map[os.Signal]signalhandler.Handler{
+		syscall.SIGHUP: func(ctx context.Context, _ os.Signal) {
+			reload(ctx, path)
+		},
+		syscall.SIGUSR1: forwardSignal,
+		syscall.SIGUSR2: forwardSignal,
+	})
+	defer stopSignals()
+
+	cmds, err := selectCommands(cfg, profileFlag, onlyFlag, exceptFlag)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	setupCgroups(cmds)
+	var procs []*process
+	for _, c := range cmds {
+		procs = append(procs, newProcess(c))
+		labelWidth = max(labelWidth, len(c.Label))
+	}
+	setProcesses(procs)
+	if len(cfg.Before) > 0 || len(cfg.After) > 0 {
+		labelWidth = max(labelWidth, len(hookBefore), len(hookAfter))
+	}
+	linkDependencies(procs)
+	closeLogs, err := openLogFiles(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeLogs()
+	for _, p := range allProcesses() {
+		if p.cfg.Watch != nil {
+			if err := p.watch(ctx); err != nil {
+				return err
+			}
+		}
 	}
 
-	if len(cfg.Cmds) < 1 {
-		return errors.New("no commands defined in rousego.toml")
+	defer forwardWindowSize()()
+	if stdinLabel != "" && tuiMode {
+		return errors.New("--stdin cannot be used together with --tui, use the i key to send input to the selected process")
+	}
+	if err := forwardStdin(); err != nil {
+		return err
 	}
 
-	ctx := context.Background()
-	c, cancel := signalhandler.Init(ctx)
+	if err := runHooks(ctx, cfg, hookBefore, cfg.Before); err != nil {
+		return err
+	}
 
-	var wgProcesses sync.WaitGroup
-	var wgMain sync.WaitGroup
+	if tuiMode {
+		if err := runTUI(ctx); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range allProcesses() {
+			p.start()
+		}
+		waitForProcesses(ctx)
+	}
 
-	defer func() {
-		signal.Stop(c)
-		cancel()
-	}()
+	// a running reload finishes first, so it does not start processes
+	// after the shutdown
+	reloadMu.Lock()
+	shutdownAll()
+	reloadMu.Unlock()
+	time.Sleep(300 * time.Millisecond)
+	slog.Info("All processes stopped")
+	if killed := hardKilled(); len(killed) > 0 {
+		slog.Warn("Processes which had to be killed: " + strings.Join(killed, ", "))
+	}
 
-	for i, cmd := range cfg.Cmds {
-		p := runCmd(&wgProcesses, int64(i), cmd.Label, cmd.Cmd)
-		processes = append(processes, p)
+	// after hooks also run if the session was interrupted
+	afterErr := runHooks(context.Background(), cfg, hookAfter, cfg.After)
+	if afterErr != nil {
+		slog.Error(afterErr.Error())
 	}
 
-	wgMain.Add(1)
-	go func() {
-		d := 2 * time.Second
-		for {
-			select {
+	if !jsonMode() {
+		printSummary(os.Stdout)
+	}
+	slog.Info("Stopping main process")
+	if err := result(); err != nil {
+		return err
+	}
+	return afterErr
+}
 
-			case <-time.After(d):
-				aliveMessage()
-			case <-ctx.Done():
-				for _, p := range processes {
-					go p.shutdown()
-				}
+// waitForProcesses returns when all processes have finished on their own or ctx is done
+func waitForProcesses(ctx context.Context) {
+	d := 2 * time.Second
+	for {
+		select {
+		case <-time.After(d):
+			aliveMessage()
+		case <-stateChanged:
+			if reason := exitCondition(); reason != "" {
+				slog.Warn("Stopping all processes: " + reason)
+				return
+			}
+			if allFinished() {
 				return
-				// default:
 			}
+		case <-ctx.Done():
+			return
 		}
-	}()
-
-	// Wait untill all started process have stopped, then release main waitgroup
-	go func() {
-		wgProcesses.Wait()
-		time.Sleep(300 * time.Millisecond)
-		slog.Info("All processes stopped")
-		wgMain.Done()
-	}()
-	wgMain.Wait()
-
-	slog.Info("Stopping main process")
-	return nil
-}
-
-func aliveMessage() {
-	// slog.Info("Still alive")
+	}
 }
 
-func runCmd(wg *sync.WaitGroup, i int64, name string, command string) *process {
-	style := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color(colors[i]))
-		// Background(lipgloss.Color("#333333"))
-	wg.Add(1)
-	run := process{Name: name, Command: command, Running: true, Style: style}
-	slog.Info("Starting: " + style.Render("["+name+"]") + " " + command)
-
-	go func() {
-		var args []string
-		args = append(args, "-c")
-		args = append(args, command)
-		// fmt.Printf("%+v\n", args)
-		// cmd := exec.CommandContext(ctx, c[0], strings.Split(c[1], " ")...)
-		cmd := exec.Command("sh", args...)
-		run.Cmd = cmd
-		// cmd.Dir = ""
-		stderr, _ := cmd.StderrPipe()
-		stdout, _ := cmd.StdoutPipe()
-
-		err := cmd.Start()
-		if err != nil {
-			// Run could also return this error and push the program
-			// termination decision to the `main` method.
-			log.Fatal(err)
+// allFinished reports whether every process has ended without being stopped.
+// Watched processes are started again on the next change.
+func allFinished() bool {
+	for _, p := range allProcesses() {
+		p.mu.Lock()
+		state := p.State
+		p.mu.Unlock()
+		if state != stateExited && state != stateSkipped {
+			return false
 		}
-
-		go func() {
-			scanner := bufio.NewScanner(stderr)
-			// scanner.Split(bufio.ScanWords)
-			for scanner.Scan() {
-				m := scanner.Text()
-				fmt.Println(style.Render("["+name+"]") + " " + m)
-			}
-		}()
-		go func() {
-			scanner := bufio.NewScanner(stdout)
-			// scanner.Split(bufio.ScanWords)
-			for scanner.Scan() {
-				m := scanner.Text()
-				fmt.Println(style.Render("["+name+"]") + " " + m)
-			}
-		}()
-
-		err = cmd.Wait()
-		if err != nil {
-			slog.Warn("Stopped: " + style.Render("["+name+"]") + " via: " + fmt.Sprint(err))
+		if state == stateExited && p.cfg.Watch != nil {
+			return false
 		}
-
-		run.Running = false
-		time.Sleep(1 * time.Second)
-		wg.Done()
-		slog.Info("Finished: " + style.Render("["+name+"]"))
-	}()
-	return &run
+	}
+	return true
 }
 
-func (p process) shutdown() {
-	if !p.Running {
-		slog.Warn("Shutting down " + p.Style.Render("["+p.Name+"]") + ": nothing todo. Process already finished")
-		return
-	}
-	slog.Info("Shutting down " + p.Style.Render("["+p.Name+"]"))
-	err := p.Cmd.Process.Kill()
-	if err != nil {
-		slog.Error(fmt.Sprint(err))
-	}
+func aliveMessage() {
+	// slog.Info("Still alive")
 }
@@ -0,0 +1,216 @@
+package rousego
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/pflag"
+)
+
+const (
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+)
+
+const (
+	processLogLines  = 5000
+	combinedLogLines = 10000
+)
+
+// rousegoLogName is the name used for rousego's own log lines
+const rousegoLogName = "rousego"
+
+// combinedLogs keeps the output of all processes and rousego's own log lines
+var combinedLogs = newLogBuffer(combinedLogLines)
+
+type logLine struct {
+	Time   time.Time
+	Name   string
+	Stream string
+	Text   string
+}
+
+// logBuffer keeps the most recent lines and passes new lines on to followers
+type logBuffer struct {
+	mu    sync.Mutex
+	lines []logLine
+	max   int
+	// version is incremented with every added line
+	version   uint64
+	followers map[chan logLine]struct{}
+}
+
+func newLogBuffer(max int) *logBuffer {
+	return &logBuffer{
+		max:       max,
+		followers: make(map[chan logLine]struct{}),
+	}
+}
+
+func (b *logBuffer) add(l logLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines = append(b.lines, l)
+	if len(b.lines) > b.max {
+		b.lines = b.lines[len(b.lines)-b.max:]
+	}
+	b.version++
+
+	for ch := range b.followers {
+		// slow followers lose lines instead of blocking the process output
+		select {
+		case ch <- l:
+		default:
+		}
+	}
+}
+
+// snapshot returns a copy of the buffered lines and the current version
+func (b *logBuffer) snapshot() ([]logLine, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]logLine(nil), b.lines...), b.version
+}
+
+func (b *logBuffer) currentVersion() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.version
+}
+
+// follow returns the buffered lines and a channel receiving all lines added
+// from now on. The returned function stops following.
+func (b *logBuffer) follow() ([]logLine, <-chan logLine, func()) {
+	ch := make(chan logLine, 256)
+	b.mu.Lock()
+	lines := append([]logLine(nil), b.lines...)
+	b.followers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return lines, ch, func() {
+		b.mu.Lock()
+		delete(b.followers, ch)
+		b.mu.Unlock()
+	}
+}
+
+const (
+	timestampsNone     = "none"
+	timestampsWall     = "wall"
+	timestampsRelative = "relative"
+)
+
+// cfgOutput configures how the lines of all processes are printed
+type cfgOutput struct {
+	// Align pads all labels to the width of the longest one
+	Align bool `toml:"align,omitempty"`
+	// Timestamps is "none" (default), "wall" for the time of day or
+	// "relative" for the time since rousego started
+	Timestamps string `toml:"timestamps,omitempty"`
+	// MarkStderr marks lines written to stderr
+	MarkStderr bool `toml:"mark_stderr,omitempty"`
+	// Format is "text" (default) or "json" for one JSON object per line
+	Format string `toml:"format,omitempty"`
+}
+
+func (o *cfgOutput) validate() error {
+	switch o.Timestamps {
+	case "":
+		o.Timestamps = timestampsNone
+	case timestampsNone, timestampsWall, timestampsRelative:
+	default:
+		return fmt.Errorf("invalid timestamps %q (expected %q, %q or %q)", o.Timestamps, timestampsNone, timestampsWall, timestampsRelative)
+	}
+	switch o.Format {
+	case "":
+		o.Format = formatText
+	case formatText, formatJSON:
+	default:
+		return fmt.Errorf("invalid output format %q (expected %q or %q)", o.Format, formatText, formatJSON)
+	}
+	return nil
+}
+
+var (
+	// output holds the output options of the config file and the flags
+	output cfgOutput
+	// labelWidth is the length of the longest label
+	labelWidth int
+	// sessionStart is the reference for relative timestamps
+	sessionStart = time.Now()
+)
+
+var (
+	timestampStyle = lipgloss.NewStyle().Faint(true)
+	stderrStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#DD0000"))
+)
+
+// applyOutputFlags lets flags which were set override the config file
+func applyOutputFlags(flags *pflag.FlagSet, cfg *cfgOutput) error {
+	if flags.Changed("align") {
+		cfg.Align, _ = flags.GetBool("align")
+	}
+	if flags.Changed("timestamps") {
+		cfg.Timestamps, _ = flags.GetString("timestamps")
+	}
+	if flags.Changed("mark-stderr") {
+		cfg.MarkStderr, _ = flags.GetBool("mark-stderr")
+	}
+	if flags.Changed("output") {
+		cfg.Format, _ = flags.GetString("output")
+	}
+	return cfg.validate()
+}
+
+// prefix returns what is printed in front of a line of the process
+func (p *process) prefix(l logLine) string {
+	var b strings.Builder
+
+	switch output.Timestamps {
+	case timestampsWall:
+		b.WriteString(timestampStyle.Render(l.Time.Format("15:04:05.000")) + " ")
+	case timestampsRelative:
+		d := l.Time.Sub(sessionStart)
+		b.WriteString(timestampStyle.Render(fmt.Sprintf("%+9.3fs", d.Seconds())) + " ")
+	}
+
+	b.WriteString(p.label())
+	if output.Align {
+		b.WriteString(strings.Repeat(" ", max(labelWidth-len(p.Name), 0)))
+	}
+
+	if output.MarkStderr {
+		switch {
+		case l.Stream == streamStderr:
+			b.WriteString(" " + stderrStyle.Render("!"))
+		case output.Align:
+			b.WriteString("  ")
+		}
+	}
+	return b.String() + " "
+}
+
+// outputLine returns the function which handles every line the process
+// writes to stream
+func (p *process) outputLine(stream string, lp *logProbe) func(string) {
+	return func(m string) {
+		l := logLine{Time: time.Now(), Name: p.Name, Stream: stream, Text: m}
+		p.logs.add(l)
+		combinedLogs.add(l)
+		p.writeLog(l)
+		switch {
+		case jsonMode():
+			r := p.record(recordLine, m)
+			r.Time = l.Time
+			r.Stream = stream
+			writeJSONRecord(r)
+		case !tuiActive.Load():
+			fmt.Println(p.prefix(l) + m)
+		}
+		lp.check(m)
+	}
+}
@@ -0,0 +1,63 @@
+package rousego
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "inspect the rousego config",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "print the fully resolved config",
+	Long: `Print the config as rousego runs it: included files are merged, variables
+are expanded, instances are expanded, defaults are filled in, paths are
+absolute and the top-level env is merged into the env of every command.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := findConfig(configFile)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+
+		out, err := toml.Marshal(cfg.resolved())
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# resolved from %s\n\n", path)
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+// resolved returns the loaded config without the settings which have
+// already been applied to the commands
+func (cfg *cfgMain) resolved() cfgMain {
+	r := *cfg
+	r.Env = nil
+	r.Vars = nil
+	r.Cmds = make([]cfgCommands, len(cfg.Cmds))
+	for i, c := range cfg.Cmds {
+		if c.templated() {
+			c.Instances = 0
+			c.Port = 0
+		}
+		r.Cmds[i] = c
+	}
+	return r
+}
+
+func init() {
+	configCmd.AddCommand(configPrintCmd)
+	Cmd.AddCommand(configCmd)
+}
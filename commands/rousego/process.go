@@ -0,0 +1,515 @@
+package rousego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	stateWaiting  = "waiting"
+	stateRunning  = "running"
+	stateBackoff  = "backoff"
+	stateStopping = "stopping"
+	stateStopped  = "stopped"
+	stateExited   = "exited"
+	stateSkipped  = "skipped"
+	// stateScheduled is the state of a scheduled command between its runs
+	stateScheduled = "scheduled"
+)
+
+type process struct {
+	Name     string
+	Cmd      *exec.Cmd
+	Running  bool
+	Command  string
+	Style    lipgloss.Style
+	Restarts int
+	// State is one of the state constants above
+	State string
+	// StartedAt is the start time of the current run
+	StartedAt time.Time
+	// LastExit describes how the last run ended
+	LastExit string
+	// ReadyState is the state of the readiness probe, empty without probe
+	ReadyState string
+	// Health is the result of the health checks of the current run, empty
+	// without healthcheck
+	Health string
+	// NextRun is the time of the next run of a scheduled command
+	NextRun time.Time
+	// Runs counts the finished runs of a scheduled command
+	Runs int
+	// HardKilled is set if the process group had to be killed with SIGKILL
+	HardKilled bool
+	// ExitCode, RunTime and FinishedAt describe the last run. Failed is set
+	// if it ended with an error without being stopped by rousego.
+	ExitCode   int
+	RunTime    time.Duration
+	FinishedAt time.Time
+	Failed     bool
+
+	cfg cfgCommands
+
+	// deps are the processes this one depends on, dependents the
+	// processes depending on this one
+	deps       []*process
+	dependents []*process
+
+	// logs keeps the most recent output lines
+	logs *logBuffer
+	// logFile receives all output lines if logging is configured
+	logFile *logFile
+	// pty is the pseudo-terminal of the current run of a command with pty = true
+	pty *os.File
+	// stdin receives the input for the current run, see wantsStdin
+	stdin io.WriteCloser
+	// unwatch stops watching files, it is set by watch
+	unwatch context.CancelFunc
+	// lastUsage is the last sample of the resource usage of the current run
+	lastUsage usage
+
+	mu       sync.Mutex
+	stopping bool
+	// policyRestarts counts the restarts by the restart policy and the
+	// health check in a row, which are limited by max_restarts. Restarts
+	// also counts the requested ones.
+	policyRestarts int
+	// restartRequested makes supervise start the process again right after
+	// the current run has been terminated
+	restartRequested bool
+	// wake interrupts the backoff delay for a requested restart
+	wake chan struct{}
+
+	// The following channels are created on every start.
+	// stop is closed by shutdown to interrupt a pending start or restart
+	stop chan struct{}
+	// ready is closed as soon as the process has been started and its
+	// readiness probe succeeded
+	ready     chan struct{}
+	readyOnce *sync.Once
+	// failed is closed if the readiness probe timed out
+	failed     chan struct{}
+	failedOnce *sync.Once
+	// done is closed once the process has finished and will not be restarted
+	done chan struct{}
+	// exited is closed once the current Cmd has been waited for
+	exited chan struct{}
+}
+
+func newProcess(cfg cfgCommands) *process {
+	return &process{
+		Name:    cfg.Label,
+		Command: cfg.commandLine(),
+		Style:   labelStyle(cfg),
+		cfg:     cfg,
+		logs:    newLogBuffer(processLogLines),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+func (p *process) label() string {
+	return p.Style.Render("[" + p.Name + "]")
+}
+
+// start runs the process in the background as soon as its dependencies are
+// ready and restarts it according to its restart policy. Nothing happens if
+// the process is already active.
+func (p *process) start() {
+	p.mu.Lock()
+	if isActive(p.State) {
+		p.mu.Unlock()
+		return
+	}
+	p.stopping = false
+	p.restartRequested = false
+	// a process started again after giving up gets the full max_restarts
+	p.policyRestarts = 0
+	p.stop = make(chan struct{})
+	p.ready = make(chan struct{})
+	p.readyOnce = &sync.Once{}
+	p.failed = make(chan struct{})
+	p.failedOnce = &sync.Once{}
+	p.done = make(chan struct{})
+	p.State = stateWaiting
+	done := p.done
+	p.mu.Unlock()
+	notifyStateChange()
+
+	go func() {
+		defer close(done)
+
+		if !p.waitForDependencies() {
+			p.setState(stateSkipped)
+			p.emit(eventSkipped, "dependencies not ready")
+			return
+		}
+
+		if p.cfg.schedule != nil {
+			slog.Info("Scheduling: " + p.label() + " " + p.Command)
+			p.runScheduled()
+		} else {
+			slog.Info("Starting: " + p.label() + " " + p.Command)
+			p.supervise()
+		}
+		if p.isStopping() {
+			p.setState(stateStopped)
+		} else {
+			p.setState(stateExited)
+		}
+		slog.Info("Finished: " + p.label())
+		if p.cfg.Watch != nil && !p.isStopping() {
+			slog.Info("Waiting for file changes: " + p.label())
+		}
+	}()
+}
+
+// signals returns the channels of the current start
+func (p *process) signals() (ready, failed, done, stop <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ready, p.failed, p.done, p.stop
+}
+
+func (p *process) supervise() {
+	delay := p.cfg.Backoff.Duration
+	_, _, _, stop := p.signals()
+
+	for {
+		started := time.Now()
+		err := p.runOnce()
+		if err != nil && !jsonMode() {
+			slog.Warn("Stopped: " + p.label() + " via: " + fmt.Sprint(err))
+		}
+
+		p.mu.Lock()
+		requested := p.restartRequested
+		p.restartRequested = false
+		p.mu.Unlock()
+
+		if p.isStopping() {
+			return
+		}
+		if requested {
+			p.mu.Lock()
+			p.Restarts++
+			p.mu.Unlock()
+			p.emit(eventRestarting, "requested")
+			delay = p.cfg.Backoff.Duration
+			continue
+		}
+
+		if !p.shouldRestart(err) {
+			return
+		}
+		// A process which ran stable for a while starts over with the initial
+		// backoff and the full max_restarts
+		p.mu.Lock()
+		if time.Since(started) >= p.cfg.MaxBackoff.Duration {
+			delay = p.cfg.Backoff.Duration
+			p.policyRestarts = 0
+		}
+		policyRestarts := p.policyRestarts
+		p.mu.Unlock()
+		if p.cfg.MaxRestarts > 0 && policyRestarts >= p.cfg.MaxRestarts {
+			if !p.emit(eventGaveUp, "reached max_restarts") {
+				slog.Error("Giving up: "+p.label()+" reached max_restarts", "restarts", policyRestarts, "exit", exitStatus(err))
+			}
+			return
+		}
+
+		p.mu.Lock()
+		p.Restarts++
+		p.policyRestarts++
+		p.mu.Unlock()
+		if !p.emit(eventRestarting, "in "+delay.String()) {
+			slog.Warn("Restarting: "+p.label()+" in "+delay.String(), "restart", p.restartsString(), "exit", exitStatus(err))
+		}
+
+		p.setState(stateBackoff)
+		select {
+		case <-time.After(delay):
+		case <-p.wake:
+			p.mu.Lock()
+			p.restartRequested = false
+			p.mu.Unlock()
+		case <-stop:
+			return
+		}
+
+		delay *= 2
+		if delay > p.cfg.MaxBackoff.Duration {
+			delay = p.cfg.MaxBackoff.Duration
+		}
+	}
+}
+
+// runOnce starts the command and blocks until it has exited
+func (p *process) runOnce() error {
+	cmd, err := p.command()
+	if err != nil {
+		return err
+	}
+	lp := newLogProbe(p.cfg.Ready)
+	var stdout, stderr *lineWriter
+	if p.cfg.Pty {
+		// stdout and stderr are the same terminal
+		stdout = newLineWriter(ptyLine(p.outputLine(streamStdout, lp)))
+		stderr = stdout
+	} else {
+		stdout = newLineWriter(p.outputLine(streamStdout, lp))
+		stderr = newLineWriter(p.outputLine(streamStderr, lp))
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		// don't wait forever for background children which keep stdout open
+		cmd.WaitDelay = time.Second
+	}
+	var stdin io.WriteCloser
+	if p.wantsStdin() && !p.cfg.Pty {
+		if stdin, err = cmd.StdinPipe(); err != nil {
+			return err
+		}
+	}
+
+	cgroup, cgroupFile := p.cgroup(cmd)
+	defer removeCgroup(cgroup)
+	p.setLimits(cmd, cgroup)
+
+	p.mu.Lock()
+	if p.stopping {
+		p.mu.Unlock()
+		if cgroupFile != nil {
+			cgroupFile.Close()
+		}
+		return nil
+	}
+	var (
+		ptmx   *os.File
+		copied <-chan struct{}
+	)
+	if p.cfg.Pty {
+		ptmx, copied, err = p.startPty(cmd, stdout)
+	} else {
+		err = cmd.Start()
+	}
+	if cgroupFile != nil {
+		cgroupFile.Close()
+	}
+	if err != nil {
+		p.LastExit = exitStatus(err)
+		p.ExitCode = exitCode(err)
+		p.RunTime = 0
+		p.FinishedAt = time.Now()
+		p.Failed = true
+		p.mu.Unlock()
+		p.emitExit(err)
+		return err
+	}
+	exited := make(chan struct{})
+	p.Cmd = cmd
+	p.pty = ptmx
+	p.stdin = stdin
+	if ptmx != nil {
+		p.stdin = ptmx
+	}
+	p.Running = true
+	p.StartedAt = time.Now()
+	p.exited = exited
+	p.mu.Unlock()
+	p.setState(stateRunning)
+	p.emit(eventStarted, p.Command)
+	p.logMarker("started pid " + strconv.Itoa(cmd.Process.Pid) + ": " + p.Command)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.probe(ctx, lp)
+	go p.healthcheck(ctx)
+
+	err = cmd.Wait()
+	close(exited)
+	if ptmx != nil {
+		// don't wait forever for background children which keep the terminal open
+		select {
+		case <-copied:
+		case <-time.After(time.Second):
+		}
+		ptmx.Close()
+	}
+	p.mu.Lock()
+	p.pty = nil
+	p.stdin = nil
+	p.mu.Unlock()
+
+	// Children which outlived the main process would keep ports and files busy.
+	// On shutdown and requested restarts terminate takes care of them already.
+	p.mu.Lock()
+	terminating := p.stopping || p.restartRequested
+	p.mu.Unlock()
+	if groupAlive(cmd.Process.Pid) && !terminating {
+		slog.Warn("Cleaning up: " + p.label() + " left processes behind")
+		p.terminate(cmd, exited)
+	}
+
+	stdout.Flush()
+	stderr.Flush()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil
+	}
+
+	p.mu.Lock()
+	p.Running = false
+	p.LastExit = exitStatus(err)
+	p.ExitCode = exitCode(err)
+	p.FinishedAt = time.Now()
+	p.RunTime = p.FinishedAt.Sub(p.StartedAt)
+	p.Failed = err != nil && !terminating
+	p.mu.Unlock()
+	p.emitExit(err)
+	p.logMarker(exitStatus(err))
+
+	if p.cfg.Type == typeOneshot && err == nil {
+		p.emit(eventReady, "finished")
+		p.markReady()
+	}
+
+	return err
+}
+
+func (p *process) shouldRestart(err error) bool {
+	switch p.cfg.Restart {
+	case restartAlways:
+		return true
+	case restartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+func (p *process) isStopping() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopping
+}
+
+func (p *process) setState(state string) {
+	p.mu.Lock()
+	p.State = state
+	p.mu.Unlock()
+	notifyStateChange()
+}
+
+func (p *process) restartsString() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cfg.MaxRestarts > 0 {
+		return strconv.Itoa(p.policyRestarts) + "/" + strconv.Itoa(p.cfg.MaxRestarts)
+	}
+	return strconv.Itoa(p.Restarts)
+}
+
+// shutdown stops the process. It is not restarted until start is called again.
+func (p *process) shutdown() {
+	p.mu.Lock()
+	if !p.stopping && p.stop != nil {
+		p.stopping = true
+		close(p.stop)
+	}
+	running := p.Running
+	cmd := p.Cmd
+	exited := p.exited
+	state := p.State
+	p.mu.Unlock()
+
+	if state == stateScheduled {
+		slog.Info("Unscheduling: " + p.label())
+		return
+	}
+	if !running {
+		slog.Warn("Shutting down " + p.label() + ": nothing todo. Process already finished")
+		return
+	}
+	p.emit(eventStopping, "via "+p.cfg.StopSignal)
+	slog.Info("Shutting down " + p.label() + " via " + p.cfg.StopSignal)
+	p.setState(stateStopping)
+	p.terminate(cmd, exited)
+}
+
+// restart stops the current run through the normal shutdown path and starts
+// the process again right away. A process which is not active is started.
+func (p *process) restart(reason string) {
+	p.mu.Lock()
+	state := p.State
+	running := p.Running
+	cmd := p.Cmd
+	exited := p.exited
+	if state == stateBackoff || state == stateScheduled || (running && state == stateRunning && !p.stopping) {
+		p.restartRequested = true
+	}
+	p.mu.Unlock()
+
+	switch {
+	case !isActive(state):
+		slog.Info("Restarting: " + p.label() + " " + reason)
+		p.start()
+	case state == stateBackoff || state == stateScheduled:
+		slog.Info("Restarting: " + p.label() + " " + reason)
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	case running && state == stateRunning:
+		slog.Info("Restarting: " + p.label() + " " + reason)
+		p.terminate(cmd, exited)
+	}
+}
+
+// stateChanged receives a value whenever a process changes its state
+var stateChanged = make(chan struct{}, 1)
+
+func notifyStateChange() {
+	select {
+	case stateChanged <- struct{}{}:
+	default:
+	}
+}
+
+// isActive reports whether a process in this state is or will be running
+func isActive(state string) bool {
+	switch state {
+	case stateWaiting, stateRunning, stateBackoff, stateStopping, stateScheduled:
+		return true
+	}
+	return false
+}
+
+// uptime returns how long the current run is going on
+func (p *process) uptime() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.Running {
+		return 0
+	}
+	return time.Since(p.StartedAt)
+}
+
+// exitStatus describes how a command ended, e.g. "exit status 1" or "signal: killed"
+func exitStatus(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.String()
+	}
+	return err.Error()
+}
@@ -0,0 +1,185 @@
+package rousego
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+)
+
+const procfileName = "Procfile"
+
+// procfileLine matches "name: command" like foreman does
+var procfileLine = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// procfileEntry is one process type of a Procfile
+type procfileEntry struct {
+	Label   string `toml:"label"`
+	Cmd     string `toml:"cmd"`
+	Dir     string `toml:"dir,omitempty"`
+	EnvFile string `toml:"env_file,omitempty"`
+}
+
+func isProcfile(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), procfileName)
+}
+
+// readProcfile parses a Procfile. Like foreman, the .env file next to it is
+// loaded into the environment of every process.
+func readProcfile(path string, b []byte) ([]procfileEntry, error) {
+	envFile := ""
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), ".env")); err == nil {
+		envFile = ".env"
+	}
+
+	var entries []procfileEntry
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := procfileLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("could not parse %s line %d: expected \"name: command\"", filepath.Base(path), n)
+		}
+		entries = append(entries, procfileEntry{Label: m[1], Cmd: m[2], EnvFile: envFile})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// procfileConfig returns a Procfile in the generic form of readConfigFile
+func procfileConfig(path string, b []byte) (map[string]any, error) {
+	entries, err := readProcfile(path, b)
+	if err != nil {
+		return nil, err
+	}
+	var cmds []any
+	for _, e := range entries {
+		c := map[string]any{"label": e.Label, "cmd": e.Cmd}
+		if e.EnvFile != "" {
+			c["env_file"] = e.EnvFile
+		}
+		cmds = append(cmds, c)
+	}
+	return map[string]any{"cmds": cmds}, nil
+}
+
+var (
+	importOutput string
+	importForce  bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "convert other process definitions into a rousego.toml",
+}
+
+var importProcfileCmd = &cobra.Command{
+	Use:   "procfile [path]",
+	Short: "convert a Procfile into a rousego.toml",
+	Long: `Convert a Procfile into a rousego.toml next to it with one [[cmds]] entry
+per process type. If there is a .env file next to the Procfile, every command
+gets env_file = ".env" like with foreman. If the new config file is written
+to another directory, the commands get the directory of the Procfile as dir.
+
+rousego also runs a Procfile directly, if there is no rousego.toml or with
+--config Procfile.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := procfileName
+		if len(args) > 0 {
+			path = args[0]
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+		entries, err := readProcfile(path, b)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return errors.New("no processes defined in " + path)
+		}
+
+		target := importTarget(path)
+		// the processes still run next to the Procfile and load its .env
+		if dir := importDir(path, target); dir != "" {
+			for i, e := range entries {
+				entries[i].Dir = dir
+				if e.EnvFile != "" {
+					entries[i].EnvFile = filepath.Join(dir, e.EnvFile)
+				}
+			}
+		}
+		return writeImport(path, "procfile", target, struct {
+			Cmds []procfileEntry `toml:"cmds"`
+		}{entries}, len(entries))
+	},
+}
+
+// importTarget returns the path of the config file converted from path
+func importTarget(path string) string {
+	if importOutput != "" {
+		return importOutput
+	}
+	return filepath.Join(filepath.Dir(path), configNames[0])
+}
+
+// importDir returns the directory of the imported file relative to the
+// directory of target, or "" if both are in the same directory. It is
+// absolute if there is no relative path.
+func importDir(path, target string) string {
+	from, err := filepath.Abs(filepath.Dir(target))
+	if err != nil {
+		return ""
+	}
+	to, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return ""
+	}
+	if from == to {
+		return ""
+	}
+	if rel, err := filepath.Rel(from, to); err == nil {
+		return rel
+	}
+	return to
+}
+
+// writeImport writes the commands converted from path to target
+func writeImport(path, kind, target string, cfg any, n int) error {
+	out, err := toml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	out = append([]byte("# converted from "+filepath.Base(path)+" by rousego import "+kind+"\n\n"), out...)
+
+	if _, err := os.Stat(target); err == nil && !importForce {
+		return errors.New(target + " already exists, use --force to overwrite it")
+	}
+	if err := os.WriteFile(target, out, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s with %d commands\n", target, n)
+	return nil
+}
+
+func init() {
+	importCmd.PersistentFlags().StringVarP(&importOutput, "output", "o", "", "path of the new config file (default: rousego.toml next to the imported file)")
+	importCmd.PersistentFlags().BoolVarP(&importForce, "force", "f", false, "overwrite an existing config file")
+
+	importCmd.AddCommand(importProcfileCmd)
+	Cmd.AddCommand(importCmd)
+}
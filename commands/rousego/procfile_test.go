@@ -0,0 +1,70 @@
+package rousego
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadProcfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		dotEnv  bool
+		want    []procfileEntry
+		wantErr bool
+	}{
+		{
+			name:    "process types",
+			content: "web: bundle exec rails s -p $PORT\nworker:bundle exec sidekiq\n",
+			want: []procfileEntry{
+				{Label: "web", Cmd: "bundle exec rails s -p $PORT"},
+				{Label: "worker", Cmd: "bundle exec sidekiq"},
+			},
+		},
+		{
+			name:    "comments and blank lines",
+			content: "# dev processes\n\n  web: npm start  \n",
+			want:    []procfileEntry{{Label: "web", Cmd: "npm start"}},
+		},
+		{
+			name:    "command with colons",
+			content: "redis_cache-1: redis-server --bind 127.0.0.1:6380\n",
+			want:    []procfileEntry{{Label: "redis_cache-1", Cmd: "redis-server --bind 127.0.0.1:6380"}},
+		},
+		{
+			name:    "env file next to the Procfile",
+			content: "web: npm start\n",
+			dotEnv:  true,
+			want:    []procfileEntry{{Label: "web", Cmd: "npm start", EnvFile: ".env"}},
+		},
+		{name: "empty", content: "", want: nil},
+		{name: "missing name", content: "npm start\n", wantErr: true},
+		{name: "missing command", content: "web:\n", wantErr: true},
+		{name: "invalid name", content: "web app: npm start\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.dotEnv {
+				if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("PORT=5000\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := readProcfile(filepath.Join(dir, procfileName), []byte(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("readProcfile() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readProcfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
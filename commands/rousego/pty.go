@@ -0,0 +1,84 @@
+package rousego
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// startPty starts cmd with a pseudo-terminal as stdin, stdout and stderr,
+// so tools keep their colors and progress output. Everything the command
+// writes is copied to w, copied is closed when the terminal is closed.
+func (p *process) startPty(cmd *exec.Cmd, w io.Writer) (ptmx *os.File, copied <-chan struct{}, err error) {
+	// the new session makes the command the leader of its own process group
+	attrs := &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if cmd.SysProcAttr != nil {
+		attrs.UseCgroupFD = cmd.SysProcAttr.UseCgroupFD
+		attrs.CgroupFD = cmd.SysProcAttr.CgroupFD
+	}
+	ptmx, err = pty.StartWithAttrs(cmd, p.ptySize(), attrs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// reading fails with EIO as soon as the command and its children exited
+		io.Copy(w, ptmx)
+	}()
+	return ptmx, done, nil
+}
+
+// ptySize returns the size of rousego's terminal minus the space of the
+// label prefix, or 80x24 if the output is no terminal
+func (p *process) ptySize() *pty.Winsize {
+	cols, rows := 80, 24
+	if w, h, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		cols, rows = w, h
+	}
+	cols = max(cols-lipgloss.Width(p.prefix(logLine{Time: time.Now()})), 20)
+	return &pty.Winsize{Cols: uint16(cols), Rows: uint16(rows)}
+}
+
+// ptyLine returns what a terminal would show for a line which contains
+// carriage returns, e.g. the last state of a progress bar
+func ptyLine(fn func(string)) func(string) {
+	return func(m string) {
+		if i := strings.LastIndexByte(m, '\r'); i >= 0 {
+			m = m[i+1:]
+		}
+		fn(m)
+	}
+}
+
+// forwardWindowSize resizes the pseudo-terminals of all processes when the
+// terminal of rousego is resized. The returned function stops forwarding.
+func forwardWindowSize() func() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGWINCH)
+	go func() {
+		for range c {
+			for _, p := range allProcesses() {
+				p.mu.Lock()
+				ptmx := p.pty
+				p.mu.Unlock()
+				if ptmx != nil {
+					pty.Setsize(ptmx, p.ptySize())
+				}
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(c)
+	}
+}
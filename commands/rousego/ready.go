@@ -0,0 +1,218 @@
+package rousego
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	readyPending = "pending"
+	readyReady   = "ready"
+	readyFailed  = "failed"
+)
+
+const (
+	defaultReadyTimeout  = 60 * time.Second
+	defaultReadyInterval = 250 * time.Millisecond
+)
+
+// cfgReady defines a readiness probe. Exactly one of TCP, HTTP, Log or File
+// has to be set.
+type cfgReady struct {
+	// TCP is an address like "localhost:8080" which has to accept connections
+	TCP string `toml:"tcp,omitempty"`
+	// HTTP is a URL which has to answer with a 2xx status code
+	HTTP string `toml:"http,omitempty"`
+	// Log is a regular expression which has to match a line on stdout or stderr
+	Log string `toml:"log,omitempty"`
+	// File is a path which has to exist
+	File string `toml:"file,omitempty"`
+
+	Timeout  duration `toml:"timeout,omitempty"`
+	Interval duration `toml:"interval,omitempty"`
+}
+
+func (r *cfgReady) validate(label string) error {
+	n := 0
+	for _, v := range []string{r.TCP, r.HTTP, r.Log, r.File} {
+		if v != "" {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("[%s] ready needs exactly one of tcp, http, log or file", label)
+	}
+
+	if r.HTTP != "" {
+		if _, err := url.ParseRequestURI(r.HTTP); err != nil {
+			return fmt.Errorf("[%s] invalid ready.http: %w", label, err)
+		}
+	}
+	if r.Log != "" {
+		if _, err := regexp.Compile(r.Log); err != nil {
+			return fmt.Errorf("[%s] invalid ready.log: %w", label, err)
+		}
+	}
+
+	if r.Timeout.Duration <= 0 {
+		r.Timeout.Duration = defaultReadyTimeout
+	}
+	if r.Interval.Duration <= 0 {
+		r.Interval.Duration = defaultReadyInterval
+	}
+	return nil
+}
+
+func (r *cfgReady) String() string {
+	switch {
+	case r.TCP != "":
+		return "tcp " + r.TCP
+	case r.HTTP != "":
+		return "http " + r.HTTP
+	case r.Log != "":
+		return "log " + r.Log
+	default:
+		return "file " + r.File
+	}
+}
+
+// check runs the probe once
+func (r *cfgReady) check(ctx context.Context) bool {
+	switch {
+	case r.TCP != "":
+		d := net.Dialer{Timeout: time.Second}
+		conn, err := d.DialContext(ctx, "tcp", r.TCP)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+
+	case r.HTTP != "":
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.HTTP, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode >= 200 && resp.StatusCode < 300
+
+	case r.File != "":
+		_, err := os.Stat(r.File)
+		return err == nil
+	}
+	return false
+}
+
+// logProbe signals as soon as an output line matches the ready.log pattern
+type logProbe struct {
+	re      *regexp.Regexp
+	once    sync.Once
+	matched chan struct{}
+}
+
+func newLogProbe(r *cfgReady) *logProbe {
+	if r == nil || r.Log == "" {
+		return nil
+	}
+	return &logProbe{
+		re:      regexp.MustCompile(r.Log),
+		matched: make(chan struct{}),
+	}
+}
+
+func (l *logProbe) check(line string) {
+	if l == nil {
+		return
+	}
+	if l.re.MatchString(line) {
+		l.once.Do(func() { close(l.matched) })
+	}
+}
+
+// probe waits until the process is ready. ctx is cancelled when the process exits.
+func (p *process) probe(ctx context.Context, lp *logProbe) {
+	r := p.cfg.Ready
+	if r == nil {
+		// a oneshot is ready when it exited successfully
+		if p.cfg.Type != typeOneshot {
+			p.markReady()
+		}
+		return
+	}
+
+	p.setReadyState(readyPending)
+	slog.Info("Probing: " + p.label() + " " + r.String())
+	started := time.Now()
+
+	probeCtx, cancel := context.WithTimeout(ctx, r.Timeout.Duration)
+	defer cancel()
+
+	ok := false
+	if lp != nil {
+		select {
+		case <-lp.matched:
+			ok = true
+		case <-probeCtx.Done():
+		}
+	} else {
+	loop:
+		for {
+			if r.check(probeCtx) {
+				ok = true
+				break
+			}
+			select {
+			case <-time.After(r.Interval.Duration):
+			case <-probeCtx.Done():
+				break loop
+			}
+		}
+	}
+
+	switch {
+	case ok:
+		p.setReadyState(readyReady)
+		p.emit(eventReady, r.String())
+		slog.Info("Ready: "+p.label()+" "+r.String(), "after", time.Since(started).Round(time.Millisecond))
+		p.markReady()
+	case ctx.Err() != nil:
+		// process exited before it became ready
+		p.setReadyState("")
+	default:
+		p.setReadyState(readyFailed)
+		if !p.emit(eventNotReady, r.String()+" timeout "+r.Timeout.String()) {
+			slog.Error("Not ready: "+p.label()+" "+r.String(), "timeout", r.Timeout.Duration)
+		}
+		p.mu.Lock()
+		failed, once := p.failed, p.failedOnce
+		p.mu.Unlock()
+		once.Do(func() { close(failed) })
+	}
+}
+
+func (p *process) markReady() {
+	p.mu.Lock()
+	ready, once := p.ready, p.readyOnce
+	p.mu.Unlock()
+	once.Do(func() { close(ready) })
+}
+
+func (p *process) setReadyState(state string) {
+	p.mu.Lock()
+	p.ReadyState = state
+	p.mu.Unlock()
+}
@@ -0,0 +1,147 @@
+package rousego
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// reloadMu prevents overlapping reloads and a reload during the final
+// shutdown
+var reloadMu sync.Mutex
+
+// reload reads the config file again and applies the changes of the
+// commands: added commands are started, removed ones are stopped and changed
+// ones are stopped and started again with their new config. The other
+// settings like [output] and [logging] keep their values.
+func reload(ctx context.Context, path string) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+
+	slog.Info("Reloading: " + path)
+	cfg, err := loadConfig(path)
+	if err != nil {
+		slog.Error("Reload failed, keeping the current config: " + err.Error())
+		return
+	}
+	cmds, err := selectCommands(cfg, profileFlag, onlyFlag, exceptFlag)
+	if err != nil {
+		slog.Error("Reload failed, keeping the current config: " + err.Error())
+		return
+	}
+
+	current := make(map[string]*process)
+	for _, p := range allProcesses() {
+		current[p.Name] = p
+	}
+
+	var procs, added, removed []*process
+	inConfig := make(map[string]bool)
+	for _, c := range cmds {
+		inConfig[c.Label] = true
+		old, ok := current[c.Label]
+		if !ok {
+			p := newProcess(c)
+			slog.Info("Reload: adding " + p.label())
+			procs = append(procs, p)
+			added = append(added, p)
+			continue
+		}
+		if fields := changedFields(old.cfg, c); len(fields) > 0 {
+			p := newProcess(c)
+			// the output so far stays visible in the TUI and for rousego logs
+			p.logs = old.logs
+			slog.Info("Reload: changing " + p.label() + " " + strings.Join(fields, ", "))
+			procs = append(procs, p)
+			added = append(added, p)
+			removed = append(removed, old)
+			continue
+		}
+		procs = append(procs, old)
+	}
+	for _, p := range allProcesses() {
+		if !inConfig[p.Name] {
+			slog.Info("Reload: removing " + p.label())
+			removed = append(removed, p)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		slog.Info("Reload: nothing changed")
+		return
+	}
+
+	// like shutdownAll, a process is stopped after the removed processes
+	// depending on it
+	stopping := make(map[*process]bool)
+	for _, p := range removed {
+		stopping[p] = true
+	}
+	var wg sync.WaitGroup
+	for _, p := range removed {
+		if p.unwatch != nil {
+			p.unwatch()
+		}
+		wg.Add(1)
+		go func(p *process) {
+			defer wg.Done()
+			_, dependents := p.links()
+			for _, d := range dependents {
+				if _, _, done, _ := d.signals(); stopping[d] && done != nil {
+					<-done
+				}
+			}
+			p.shutdown()
+			if _, _, done, _ := p.signals(); done != nil {
+				<-done
+			}
+			p.logFile.Close()
+		}(p)
+	}
+	wg.Wait()
+	// the session ended while the removed processes were stopped, the
+	// shutdown stops the remaining ones of the current list
+	if ctx.Err() != nil {
+		slog.Info("Reload: aborted, rousego is stopping")
+		return
+	}
+
+	linkDependencies(procs)
+	for _, p := range added {
+		if err := p.openLog(cfg); err != nil {
+			slog.Error(err.Error())
+		}
+		if p.cfg.Watch != nil {
+			if err := p.watch(ctx); err != nil {
+				slog.Error(err.Error())
+			}
+		}
+	}
+	setProcesses(procs)
+	notifyStateChange()
+	for _, p := range added {
+		p.start()
+	}
+}
+
+// changedFields returns the config keys in which two commands differ
+func changedFields(a, b cfgCommands) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		f := va.Type().Field(i)
+		// unexported fields are derived from the exported ones
+		if !f.IsExported() {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
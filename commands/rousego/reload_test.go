@@ -0,0 +1,42 @@
+package rousego
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChangedFields(t *testing.T) {
+	base := cfgCommands{
+		Label:     "Api",
+		Cmd:       "go run .",
+		Env:       map[string]string{"PORT": "8080"},
+		DependsOn: []string{"Db"},
+		Backoff:   duration{time.Second},
+	}
+	tests := []struct {
+		name   string
+		change func(c *cfgCommands)
+		want   []string
+	}{
+		{"unchanged", func(c *cfgCommands) {}, nil},
+		{"cmd", func(c *cfgCommands) { c.Cmd = "go run ./cmd" }, []string{"cmd"}},
+		{"env value", func(c *cfgCommands) { c.Env = map[string]string{"PORT": "8081"} }, []string{"env"}},
+		{"same env in a new map", func(c *cfgCommands) { c.Env = map[string]string{"PORT": "8080"} }, nil},
+		{"depends_on", func(c *cfgCommands) { c.DependsOn = nil }, []string{"depends_on"}},
+		{"duration", func(c *cfgCommands) { c.Backoff = duration{2 * time.Second} }, []string{"backoff"}},
+		{"pointer", func(c *cfgCommands) { c.Ready = &cfgReady{TCP: "localhost:8080"} }, []string{"ready"}},
+		{"unexported", func(c *cfgCommands) { c.configDir = "/elsewhere" }, nil},
+		{"several in field order", func(c *cfgCommands) { c.Restart = "always"; c.Cmd = "make" }, []string{"cmd", "restart"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the changes replace fields instead of modifying the shared maps
+			changed := base
+			tt.change(&changed)
+			if got := changedFields(base, changed); !slices.Equal(got, tt.want) {
+				t.Errorf("changedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
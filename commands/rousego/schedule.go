@@ -0,0 +1,101 @@
+package rousego
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// everySchedule runs a command in a fixed interval
+type everySchedule time.Duration
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// validateSchedule parses schedule or every of a command
+func (c *cfgCommands) validateSchedule() error {
+	if c.Schedule == "" && c.Every.Duration == 0 {
+		return nil
+	}
+
+	switch {
+	case c.Schedule != "" && c.Every.Duration != 0:
+		return fmt.Errorf("[%s] schedule and every must not be used together", c.Label)
+	case c.Type == typeOneshot:
+		return fmt.Errorf("[%s] a oneshot command cannot be scheduled", c.Label)
+	case c.Restart != restartNo:
+		return fmt.Errorf("[%s] a scheduled command cannot use restart", c.Label)
+	case c.Ready != nil:
+		return fmt.Errorf("[%s] a scheduled command has no ready probe", c.Label)
+	}
+
+	if c.Schedule != "" {
+		schedule, err := cron.ParseStandard(c.Schedule)
+		if err != nil {
+			return fmt.Errorf("[%s] invalid schedule %q: %w", c.Label, c.Schedule, err)
+		}
+		c.schedule = schedule
+		return nil
+	}
+	if c.Every.Duration < 0 {
+		return fmt.Errorf("[%s] every must not be negative", c.Label)
+	}
+	c.schedule = everySchedule(c.Every.Duration)
+	return nil
+}
+
+// runScheduled runs the command on its schedule until the process is
+// stopped. A run which is due while the previous one is still going is
+// skipped.
+func (p *process) runScheduled() {
+	_, _, _, stop := p.signals()
+	// dependents don't wait for periodic jobs
+	p.markReady()
+
+	next := p.cfg.schedule.Next(time.Now())
+	for {
+		p.mu.Lock()
+		p.NextRun = next
+		p.mu.Unlock()
+		p.setState(stateScheduled)
+
+		select {
+		case <-time.After(time.Until(next)):
+		case <-p.wake:
+			// run now, requested via restart
+		case <-stop:
+			return
+		}
+
+		p.mu.Lock()
+		p.NextRun = time.Time{}
+		p.restartRequested = false
+		p.mu.Unlock()
+
+		started := time.Now()
+		if err := p.runOnce(); err != nil && !p.isStopping() {
+			slog.Warn("Failed: " + p.label() + " via: " + fmt.Sprint(err))
+		}
+		p.mu.Lock()
+		p.restartRequested = false
+		p.mu.Unlock()
+		if p.isStopping() {
+			return
+		}
+
+		next = p.cfg.schedule.Next(started)
+		now := time.Now()
+		if !next.After(now) {
+			slog.Warn("Skipping: " + p.label() + " run at " + next.Format("15:04:05") + " because the previous run was still running")
+			for !next.After(now) {
+				next = p.cfg.schedule.Next(next)
+			}
+		}
+		p.mu.Lock()
+		p.Runs++
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,115 @@
+package rousego
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	onlyFlag    []string
+	exceptFlag  []string
+	profileFlag string
+)
+
+// cfgProfile is a named selection of commands. Only and Except contain
+// labels or group names.
+type cfgProfile struct {
+	Only   []string `toml:"only,omitempty"`
+	Except []string `toml:"except,omitempty"`
+}
+
+// hasName reports whether name is the label or one of the groups of the
+// command. Instances also have the label of the scaled command.
+func (c *cfgCommands) hasName(name string) bool {
+	return c.Label == name || (c.base != "" && c.base == name) || slices.Contains(c.Groups, name)
+}
+
+// checkNames returns an error for names which are neither a label nor a group
+func checkNames(cmds []cfgCommands, names []string) error {
+	for _, name := range names {
+		found := false
+		for i := range cmds {
+			if cmds[i].hasName(name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown command or group %q", name)
+		}
+	}
+	return nil
+}
+
+// selectCommands returns the commands selected by the profile and the
+// --only and --except names. Dependencies of selected commands are always
+// included.
+func selectCommands(cfg *cfgMain, profile string, only, except []string) ([]cfgCommands, error) {
+	if profile != "" {
+		p, ok := cfg.Profiles[profile]
+		if !ok {
+			var names []string
+			for name := range cfg.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				return nil, fmt.Errorf("unknown profile %q, %s defines no profiles", profile, cfg.file)
+			}
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(names, ", "))
+		}
+		only = append(append([]string{}, p.Only...), only...)
+		except = append(append([]string{}, p.Except...), except...)
+	}
+	if len(only) == 0 && len(except) == 0 {
+		return cfg.Cmds, nil
+	}
+	if err := checkNames(cfg.Cmds, append(append([]string{}, only...), except...)); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]bool)
+	for i := range cfg.Cmds {
+		c := &cfg.Cmds[i]
+		if len(only) > 0 && !slices.ContainsFunc(only, c.hasName) {
+			continue
+		}
+		if slices.ContainsFunc(except, c.hasName) {
+			continue
+		}
+		selected[c.Label] = true
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("no commands selected")
+	}
+
+	byLabel := make(map[string]*cfgCommands)
+	for i := range cfg.Cmds {
+		byLabel[cfg.Cmds[i].Label] = &cfg.Cmds[i]
+	}
+	var add func(c *cfgCommands)
+	add = func(c *cfgCommands) {
+		for _, dep := range c.DependsOn {
+			if !selected[dep] {
+				selected[dep] = true
+				slog.Info("Adding: [" + dep + "] as dependency of [" + c.Label + "]")
+				add(byLabel[dep])
+			}
+		}
+	}
+	for label := range selected {
+		add(byLabel[label])
+	}
+
+	var cmds []cfgCommands
+	for _, c := range cfg.Cmds {
+		if selected[c.Label] {
+			cmds = append(cmds, c)
+		}
+	}
+	return cmds, nil
+}
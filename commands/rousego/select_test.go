@@ -0,0 +1,74 @@
+package rousego
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelectCommands(t *testing.T) {
+	cfg := &cfgMain{
+		Cmds: []cfgCommands{
+			{Label: "Db"},
+			{Label: "Cache"},
+			{Label: "Api", DependsOn: []string{"Db", "Cache"}},
+			{Label: "Web", Groups: []string{"frontend"}, DependsOn: []string{"Api"}},
+			{Label: "Docs", Groups: []string{"frontend"}},
+		},
+		Profiles: map[string]cfgProfile{
+			"docs": {Only: []string{"Docs"}},
+		},
+	}
+	tests := []struct {
+		name    string
+		profile string
+		only    []string
+		except  []string
+		want    []string
+	}{
+		{"all", "", nil, nil, []string{"Db", "Cache", "Api", "Web", "Docs"}},
+		{"without dependencies", "", []string{"Db"}, nil, []string{"Db"}},
+		{"direct dependencies", "", []string{"Api"}, nil, []string{"Db", "Cache", "Api"}},
+		{"transitive dependencies", "", []string{"Web"}, nil, []string{"Db", "Cache", "Api", "Web"}},
+		{"group", "", []string{"frontend"}, nil, []string{"Db", "Cache", "Api", "Web", "Docs"}},
+		{"excluded dependency is added", "", []string{"Api"}, []string{"Db"}, []string{"Db", "Cache", "Api"}},
+		{"except", "", nil, []string{"frontend"}, []string{"Db", "Cache", "Api"}},
+		{"profile", "docs", nil, nil, []string{"Docs"}},
+		{"profile and only", "docs", []string{"Api"}, nil, []string{"Db", "Cache", "Api", "Docs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds, err := selectCommands(cfg, tt.profile, tt.only, tt.except)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range cmds {
+				got = append(got, c.Label)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectCommands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectCommandsErrors(t *testing.T) {
+	cfg := &cfgMain{Cmds: []cfgCommands{{Label: "Api"}}}
+	tests := []struct {
+		name    string
+		profile string
+		only    []string
+		except  []string
+	}{
+		{"unknown name", "", []string{"Web"}, nil},
+		{"unknown profile", "dev", nil, nil},
+		{"nothing selected", "", nil, []string{"Api"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := selectCommands(cfg, tt.profile, tt.only, tt.except); err == nil {
+				t.Error("selectCommands() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package rousego
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+}
+
+// parseSignal accepts signal names like "SIGTERM" or "TERM" and signal numbers
+func parseSignal(name string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+	s, ok := signalNames[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return 0, fmt.Errorf("unknown signal %q", name)
+	}
+	return s, nil
+}
+
+// signalName returns the name of a signal like "SIGUSR1"
+func signalName(sig syscall.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return "SIG" + name
+		}
+	}
+	return sig.String()
+}
+
+// forwardSignal sends a signal rousego received to the process groups of
+// all running processes which list it in forward_signals
+func forwardSignal(_ context.Context, s os.Signal) {
+	sig, ok := s.(syscall.Signal)
+	if !ok {
+		return
+	}
+	forwarded := false
+	for _, p := range allProcesses() {
+		if !slices.Contains(p.cfg.forwardSignals, sig) {
+			continue
+		}
+		forwarded = true
+		p.mu.Lock()
+		cmd := p.Cmd
+		running := p.Running
+		p.mu.Unlock()
+		if !running {
+			continue
+		}
+		slog.Info("Forwarding: " + signalName(sig) + " to " + p.label())
+		if err := syscall.Kill(-cmd.Process.Pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			slog.Error("Could not send " + signalName(sig) + " to " + p.label() + ": " + fmt.Sprint(err))
+		}
+	}
+	if !forwarded {
+		slog.Warn("Ignoring: " + signalName(sig) + ", no command lists it in forward_signals")
+	}
+}
@@ -0,0 +1,44 @@
+package rousego
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{name: "SIGTERM", want: syscall.SIGTERM},
+		{name: "TERM", want: syscall.SIGTERM},
+		{name: "sigint", want: syscall.SIGINT},
+		{name: "usr1", want: syscall.SIGUSR1},
+		{name: "SIGKILL", want: syscall.SIGKILL},
+		{name: "9", want: syscall.SIGKILL},
+		{name: "15", want: syscall.SIGTERM},
+		{name: "", wantErr: true},
+		{name: "0", wantErr: true},
+		{name: "-1", wantErr: true},
+		{name: "SIGFOO", wantErr: true},
+		{name: "SIG", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSignal(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSignal(%q) = %v, want an error", tt.name, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("parseSignal(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
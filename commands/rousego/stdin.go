@@ -0,0 +1,95 @@
+package rousego
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// stdinLabel is the label of the process which receives rousego's stdin
+var stdinLabel string
+
+// wantsStdin reports whether the process gets a pipe as stdin. Other
+// processes read from /dev/null, commands with pty = true read from their
+// terminal.
+func (p *process) wantsStdin() bool {
+	return tuiMode || p.Name == stdinLabel
+}
+
+// writeStdin passes input to the current run of the process
+func (p *process) writeStdin(b []byte) error {
+	p.mu.Lock()
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	if stdin == nil {
+		return errors.New(p.Name + " is not running")
+	}
+	_, err := stdin.Write(b)
+	return err
+}
+
+// waitForStdin waits while the process has not been started yet or is
+// about to be started again, so input piped into rousego is not lost
+func (p *process) waitForStdin() {
+	for {
+		p.mu.Lock()
+		state, stdin := p.State, p.stdin
+		p.mu.Unlock()
+		switch {
+		case stdin != nil:
+			return
+		case state == "", state == stateWaiting, state == stateBackoff:
+			time.Sleep(50 * time.Millisecond)
+		default:
+			return
+		}
+	}
+}
+
+// forwardStdin copies rousego's stdin to the process given with --stdin.
+// Input while the process is not running is dropped. At the end of stdin,
+// stdin of the process is closed.
+func forwardStdin() error {
+	if stdinLabel == "" {
+		return nil
+	}
+	if findProcess(stdinLabel) == nil {
+		return errors.New("unknown process " + stdinLabel + " for --stdin")
+	}
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			// a reload may have replaced or removed the process
+			p := findProcess(stdinLabel)
+			if p == nil {
+				slog.Warn("Dropping input: " + stdinLabel + " is not configured anymore")
+				if err != nil {
+					return
+				}
+				continue
+			}
+			if n > 0 {
+				p.waitForStdin()
+				if err := p.writeStdin(buf[:n]); err != nil {
+					slog.Warn("Dropping input: " + err.Error())
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					p.mu.Lock()
+					if p.stdin != nil && p.pty == nil {
+						p.stdin.Close()
+					}
+					p.mu.Unlock()
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
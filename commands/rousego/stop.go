@@ -0,0 +1,72 @@
+package rousego
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+const defaultStopTimeout = 10 * time.Second
+
+// terminate sends the stop signal to the process group of cmd and kills the
+// whole group if it has not finished after stop_timeout. exited has to be
+// closed as soon as cmd has been waited for.
+func (p *process) terminate(cmd *exec.Cmd, exited <-chan struct{}) {
+	pgid := cmd.Process.Pid
+	if err := syscall.Kill(-pgid, p.cfg.stopSignal); err != nil && !errors.Is(err, syscall.ESRCH) {
+		slog.Error("Could not send " + p.cfg.StopSignal + " to " + p.label() + ": " + fmt.Sprint(err))
+	}
+
+	if waitForGroup(pgid, exited, p.cfg.StopTimeout.Duration) {
+		return
+	}
+
+	if !p.emit(eventKilled, "did not stop within "+p.cfg.StopTimeout.String()) {
+		slog.Warn("Killing: " + p.label() + " did not stop within " + p.cfg.StopTimeout.String())
+	}
+	if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		slog.Error("Could not kill " + p.label() + ": " + fmt.Sprint(err))
+	}
+	p.mu.Lock()
+	p.HardKilled = true
+	p.mu.Unlock()
+}
+
+// waitForGroup waits until the group leader has been waited for and no other
+// process of the group is left. It returns false on timeout.
+func waitForGroup(pgid int, exited <-chan struct{}, timeout time.Duration) bool {
+	deadline := time.After(timeout)
+	select {
+	case <-exited:
+	case <-deadline:
+		return false
+	}
+	for groupAlive(pgid) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			return false
+		}
+	}
+	return true
+}
+
+func groupAlive(pgid int) bool {
+	return syscall.Kill(-pgid, 0) == nil
+}
+
+// hardKilled returns the labels of all processes which had to be killed with SIGKILL
+func hardKilled() []string {
+	var labels []string
+	for _, p := range allProcesses() {
+		p.mu.Lock()
+		if p.HardKilled {
+			labels = append(labels, p.Name)
+		}
+		p.mu.Unlock()
+	}
+	return labels
+}
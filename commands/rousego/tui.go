@@ -0,0 +1,512 @@
+package rousego
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const tuiRefresh = 250 * time.Millisecond
+
+// tuiActive is set while the terminal UI owns the terminal
+var tuiActive atomic.Bool
+
+const (
+	inputNone = iota
+	inputSearch
+	inputFilter
+	inputStdin
+)
+
+var (
+	tuiSidebarStyle  = lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderRight(true).PaddingRight(1)
+	tuiSelectedStyle = lipgloss.NewStyle().Reverse(true)
+	tuiHeaderStyle   = lipgloss.NewStyle().Bold(true)
+	tuiHelpStyle     = lipgloss.NewStyle().Faint(true)
+	tuiMatchStyle    = lipgloss.NewStyle().Reverse(true)
+	tuiRousegoStyle  = lipgloss.NewStyle().Faint(true)
+
+	tuiStateStyles = map[string]lipgloss.Style{
+		stateWaiting:   lipgloss.NewStyle().Foreground(lipgloss.Color("#BBBB00")),
+		stateRunning:   lipgloss.NewStyle().Foreground(lipgloss.Color("#00BB00")),
+		stateBackoff:   lipgloss.NewStyle().Foreground(lipgloss.Color("#BBBB00")),
+		stateStopping:  lipgloss.NewStyle().Foreground(lipgloss.Color("#BBBB00")),
+		stateStopped:   lipgloss.NewStyle().Faint(true),
+		stateExited:    lipgloss.NewStyle().Faint(true),
+		stateSkipped:   lipgloss.NewStyle().Foreground(lipgloss.Color("#BB0000")),
+		stateScheduled: lipgloss.NewStyle().Faint(true),
+	}
+	tuiStateSymbols = map[string]string{
+		stateWaiting:   "◌",
+		stateRunning:   "●",
+		stateBackoff:   "↻",
+		stateStopping:  "◍",
+		stateStopped:   "■",
+		stateExited:    "○",
+		stateSkipped:   "✗",
+		stateScheduled: "◷",
+	}
+	// tuiUnhealthyStyle colors the state symbol of a process failing its health check
+	tuiUnhealthyStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#BB0000"))
+)
+
+const tuiHelp = "↑/↓ select  s start  x stop  r restart  i input  / search  f filter  n/N next/prev match  esc clear  pgup/pgdn/g/G scroll  q quit"
+
+type tuiTickMsg time.Time
+
+type tuiModel struct {
+	// selected is 0 for the combined view, otherwise the index in processes + 1
+	selected int
+
+	viewport  viewport.Model
+	input     textinput.Model
+	inputMode int
+	search    string
+	filter    string
+	matches   []int
+	match     int
+
+	// rendered remembers what the viewport shows to avoid needless updates
+	rendered       uint64
+	renderedFor    int
+	renderedSearch string
+	renderedFilter string
+
+	width   int
+	height  int
+	message string
+}
+
+// runTUI starts all processes and shows them in an interactive terminal UI
+// until the user quits or ctx is done
+func runTUI(ctx context.Context) error {
+	// rousego's own log lines go to the combined view instead of stderr
+	previous := slog.Default()
+	slog.SetDefault(slog.New(log.NewWithOptions(newLineWriter(func(m string) {
+		combinedLogs.add(logLine{Time: time.Now(), Name: rousegoLogName, Stream: streamStderr, Text: m})
+	}), log.Options{
+		ReportTimestamp: true,
+		TimeFormat:      "15:04:05",
+	})))
+	defer slog.SetDefault(previous)
+
+	tuiActive.Store(true)
+	defer tuiActive.Store(false)
+
+	for _, p := range allProcesses() {
+		p.start()
+	}
+
+	input := textinput.New()
+	input.Prompt = "/"
+	m := tuiModel{
+		viewport: viewport.New(0, 0),
+		input:    input,
+	}
+
+	program := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithContext(ctx))
+	_, err := program.Run()
+	if err != nil && ctx.Err() != nil {
+		// quit because of a signal
+		return nil
+	}
+	return err
+}
+
+func tuiTick() tea.Cmd {
+	return tea.Tick(tuiRefresh, func(t time.Time) tea.Msg {
+		return tuiTickMsg(t)
+	})
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tuiTick()
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.resize()
+		m.refresh(true)
+		return m, nil
+
+	case tuiTickMsg:
+		if reason := exitCondition(); reason != "" {
+			slog.Warn("Stopping all processes: " + reason)
+			return m, tea.Quit
+		}
+		m.refresh(false)
+		return m, tuiTick()
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		if m.inputMode != inputNone {
+			return m.updateInput(msg)
+		}
+		return m.updateKey(msg)
+	}
+	return m, nil
+}
+
+func (m tuiModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		if m.inputMode == inputStdin {
+			// stay in input mode for the next line
+			if p := m.selectedProcess(); p != nil {
+				if err := p.writeStdin([]byte(m.input.Value() + "\n")); err != nil {
+					m.message = err.Error()
+					m.inputMode = inputNone
+					m.input.Blur()
+				}
+			}
+			m.input.SetValue("")
+			return m, nil
+		}
+		mode := m.inputMode
+		if mode == inputSearch {
+			m.search = m.input.Value()
+		} else {
+			m.filter = m.input.Value()
+		}
+		m.inputMode = inputNone
+		m.input.Blur()
+		m.refresh(true)
+		if mode == inputSearch && len(m.matches) > 0 {
+			m.match = len(m.matches) - 1
+			m.viewport.SetYOffset(m.matches[m.match])
+		}
+		return m, nil
+	case "esc", "ctrl+c":
+		m.inputMode = inputNone
+		m.input.Blur()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) updateKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+
+	case "up", "k":
+		if m.selected > 0 {
+			m.selected--
+			m.refresh(true)
+		}
+	case "down", "j":
+		if m.selected < len(allProcesses()) {
+			m.selected++
+			m.refresh(true)
+		}
+
+	case "s", "x", "r":
+		p := m.selectedProcess()
+		if p == nil {
+			m.message = "select a process first"
+			return m, nil
+		}
+		switch msg.String() {
+		case "s":
+			m.message = "starting " + p.Name
+			go p.start()
+		case "x":
+			m.message = "stopping " + p.Name
+			go p.shutdown()
+		case "r":
+			m.message = "restarting " + p.Name
+			go p.restart("(requested in TUI)")
+		}
+
+	case "i":
+		p := m.selectedProcess()
+		if p == nil {
+			m.message = "select a process first"
+			return m, nil
+		}
+		m.message = ""
+		m.inputMode = inputStdin
+		m.input.Prompt = p.Name + "> "
+		m.input.SetValue("")
+		return m, m.input.Focus()
+
+	case "/", "f":
+		m.inputMode = inputSearch
+		m.input.Prompt = "search: "
+		m.input.SetValue(m.search)
+		if msg.String() == "f" {
+			m.inputMode = inputFilter
+			m.input.Prompt = "filter: "
+			m.input.SetValue(m.filter)
+		}
+		m.input.CursorEnd()
+		return m, m.input.Focus()
+
+	case "n", "N":
+		if len(m.matches) == 0 {
+			return m, nil
+		}
+		if msg.String() == "n" {
+			m.match = (m.match + 1) % len(m.matches)
+		} else {
+			m.match = (m.match - 1 + len(m.matches)) % len(m.matches)
+		}
+		m.viewport.SetYOffset(m.matches[m.match])
+
+	case "esc":
+		m.search = ""
+		m.filter = ""
+		m.message = ""
+		m.refresh(true)
+
+	case "pgup", "b":
+		m.viewport.PageUp()
+	case "pgdown", " ":
+		m.viewport.PageDown()
+	case "ctrl+u":
+		m.viewport.HalfPageUp()
+	case "ctrl+d":
+		m.viewport.HalfPageDown()
+	case "home", "g":
+		m.viewport.GotoTop()
+	case "end", "G":
+		m.viewport.GotoBottom()
+	case "left", "h":
+		m.viewport.ScrollLeft(4)
+	case "right", "l":
+		m.viewport.ScrollRight(4)
+	}
+	return m, nil
+}
+
+func (m *tuiModel) selectedProcess() *process {
+	procs := allProcesses()
+	if m.selected == 0 || m.selected > len(procs) {
+		return nil
+	}
+	return procs[m.selected-1]
+}
+
+func (m *tuiModel) sidebarWidth() int {
+	w := len("All")
+	for _, p := range allProcesses() {
+		w = max(w, lipgloss.Width(p.Name))
+	}
+	// selection marker, state symbol, name, restarts and uptime
+	return w + 20
+}
+
+func (m *tuiModel) resize() {
+	m.viewport.Width = max(m.width-m.sidebarWidth()-2, 10)
+	// header, input or message line and help
+	m.viewport.Height = max(m.height-3, 1)
+}
+
+// refresh updates the log pane if new lines arrived or the view changed
+func (m *tuiModel) refresh(force bool) {
+	buffer := combinedLogs
+	if p := m.selectedProcess(); p != nil {
+		buffer = p.logs
+	}
+
+	version := buffer.currentVersion()
+	if !force && version == m.rendered && m.selected == m.renderedFor &&
+		m.search == m.renderedSearch && m.filter == m.renderedFilter {
+		return
+	}
+
+	lines, version := buffer.snapshot()
+	follow := m.viewport.AtBottom()
+	m.matches = m.matches[:0]
+
+	var b strings.Builder
+	n := 0
+	for _, l := range lines {
+		if m.filter != "" && !containsFold(l.Text, m.filter) {
+			continue
+		}
+		if n > 0 {
+			b.WriteByte('\n')
+		}
+		if m.selected == 0 {
+			b.WriteString(tuiLinePrefix(l))
+		}
+		if m.search != "" && containsFold(l.Text, m.search) {
+			m.matches = append(m.matches, n)
+			b.WriteString(highlight(ansi.Strip(l.Text), m.search))
+		} else {
+			b.WriteString(l.Text)
+		}
+		n++
+	}
+
+	m.viewport.SetContent(b.String())
+	if follow || m.selected != m.renderedFor {
+		m.viewport.GotoBottom()
+	}
+
+	m.rendered = version
+	m.renderedFor = m.selected
+	m.renderedSearch = m.search
+	m.renderedFilter = m.filter
+}
+
+func tuiLinePrefix(l logLine) string {
+	if l.Name == rousegoLogName {
+		return tuiRousegoStyle.Render("["+l.Name+"]") + " "
+	}
+	for _, p := range allProcesses() {
+		if p.Name == l.Name {
+			return p.prefix(l)
+		}
+	}
+	return "[" + l.Name + "] "
+}
+
+func (m tuiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	sidebar := tuiSidebarStyle.
+		Width(m.sidebarWidth()).
+		Height(m.height - 1).
+		Render(m.sidebarView())
+
+	header := "All processes"
+	if p := m.selectedProcess(); p != nil {
+		u := p.usage()
+		p.mu.Lock()
+		header = p.Name + " · " + p.State
+		if p.LastExit != "" && !p.Running {
+			header += " · " + p.LastExit
+		}
+		if p.Running {
+			header += fmt.Sprintf(" · %s · %.1f%% CPU", formatBytes(u.RSS), u.CPU)
+		}
+		p.mu.Unlock()
+	}
+	if m.filter != "" {
+		header += " · filter: " + m.filter
+	}
+	if m.search != "" {
+		header += fmt.Sprintf(" · search: %s (%d)", m.search, len(m.matches))
+	}
+
+	status := tuiHelpStyle.Render(m.message)
+	if m.inputMode != inputNone {
+		status = m.input.View()
+	}
+
+	pane := lipgloss.JoinVertical(lipgloss.Left,
+		tuiHeaderStyle.Render(ansi.Truncate(header, m.viewport.Width, "…")),
+		m.viewport.View(),
+		status,
+	)
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, sidebar, " ", pane),
+		tuiHelpStyle.Render(ansi.Truncate(tuiHelp, m.width, "…")),
+	)
+}
+
+func (m tuiModel) sidebarView() string {
+	rows := []string{m.sidebarRow(0, "All", "")}
+	for i, p := range allProcesses() {
+		p.mu.Lock()
+		state := p.State
+		restarts := p.Restarts
+		nextRun := p.NextRun
+		health := p.Health
+		p.mu.Unlock()
+
+		symbol := tuiStateStyles[state].Render(tuiStateSymbols[state])
+		if health == healthUnhealthy {
+			symbol = tuiUnhealthyStyle.Render(tuiStateSymbols[state])
+		}
+		info := ""
+		if restarts > 0 {
+			info += fmt.Sprintf("↻%d ", restarts)
+		}
+		if up := p.uptime(); up > 0 {
+			info += formatUptime(up)
+		} else if !nextRun.IsZero() {
+			info += "next " + nextRun.Format("15:04:05")
+		} else {
+			info += state
+		}
+		rows = append(rows, m.sidebarRow(i+1, symbol+" "+p.Style.Render(p.Name), info))
+	}
+	return strings.Join(rows, "\n")
+}
+
+func (m tuiModel) sidebarRow(i int, name string, info string) string {
+	// the sidebar is padded by one column
+	w := m.sidebarWidth() - 1
+	gap := max(w-2-lipgloss.Width(name)-lipgloss.Width(info), 1)
+	row := name + strings.Repeat(" ", gap) + tuiHelpStyle.Render(info)
+	if i == m.selected {
+		return tuiSelectedStyle.Render("▸") + " " + row
+	}
+	return "  " + row
+}
+
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := int(d.Hours())
+	mi := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm", h, mi)
+	case mi > 0:
+		return fmt.Sprintf("%dm%02ds", mi, s)
+	default:
+		return fmt.Sprintf("%ds", s)
+	}
+}
+
+func containsFold(s string, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// highlight marks all case insensitive occurrences of substr in s
+func highlight(s string, substr string) string {
+	lower := strings.ToLower(s)
+	needle := strings.ToLower(substr)
+	if len(lower) != len(s) {
+		// lower casing changed the byte positions
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(lower, needle)
+		if i < 0 || needle == "" {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:i])
+		b.WriteString(tuiMatchStyle.Render(s[i : i+len(needle)]))
+		s = s[i+len(needle):]
+		lower = lower[i+len(needle):]
+	}
+}
@@ -0,0 +1,109 @@
+package rousego
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// clockTicks is the unit of the CPU times in /proc/<pid>/stat (USER_HZ)
+	clockTicks = 100
+	// usageInterval is the minimum time between two samples of a process
+	usageInterval = time.Second
+)
+
+// usage is the resource usage of all processes in the process group of a command
+type usage struct {
+	// RSS is the resident memory in bytes
+	RSS int64
+	// CPU is the CPU usage since the previous sample in percent of one core
+	CPU float64
+
+	sampled time.Time
+	ticks   uint64
+}
+
+// usage returns the resource usage of the current run. It is sampled from
+// /proc at most once per usageInterval.
+func (p *process) usage() usage {
+	p.mu.Lock()
+	if !p.Running || p.Cmd == nil || p.Cmd.Process == nil {
+		p.lastUsage = usage{}
+		p.mu.Unlock()
+		return usage{}
+	}
+	last := p.lastUsage
+	pid := p.Cmd.Process.Pid
+	p.mu.Unlock()
+
+	if time.Since(last.sampled) < usageInterval {
+		return last
+	}
+
+	rss, ticks := groupStat(pid)
+	now := time.Now()
+	u := usage{RSS: rss, sampled: now, ticks: ticks}
+	// CPU time of exited processes is not counted anymore
+	if !last.sampled.IsZero() && ticks >= last.ticks {
+		elapsed := now.Sub(last.sampled).Seconds()
+		u.CPU = float64(ticks-last.ticks) / clockTicks / elapsed * 100
+	}
+
+	p.mu.Lock()
+	p.lastUsage = u
+	p.mu.Unlock()
+	return u
+}
+
+var pageSize = int64(os.Getpagesize())
+
+// groupStat sums up the resident memory and the CPU time of all processes
+// in the process group
+func groupStat(pgid int) (rss int64, ticks uint64) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, 0
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		b, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// the command name in parentheses may contain spaces
+		i := strings.LastIndexByte(string(b), ')')
+		if i < 0 {
+			continue
+		}
+		// fields after the name, starting with state (field 3)
+		f := strings.Fields(string(b[i+1:]))
+		if len(f) < 22 {
+			continue
+		}
+		if group, _ := strconv.Atoi(f[2]); group != pgid {
+			continue
+		}
+		utime, _ := strconv.ParseUint(f[11], 10, 64)
+		stime, _ := strconv.ParseUint(f[12], 10, 64)
+		pages, _ := strconv.ParseInt(f[21], 10, 64)
+		ticks += utime + stime
+		rss += pages * pageSize
+	}
+	return rss, ticks
+}
+
+// formatBytes formats a size like "12.3MB"
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return strconv.FormatFloat(float64(n)/(1<<30), 'f', 1, 64) + "GB"
+	case n >= 1<<20:
+		return strconv.FormatFloat(float64(n)/(1<<20), 'f', 1, 64) + "MB"
+	default:
+		return strconv.FormatInt(n>>10, 10) + "KB"
+	}
+}
@@ -0,0 +1,76 @@
+package rousego
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+)
+
+// varPattern matches ${name} and ${name:-default}. $${ is an escaped ${
+var varPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// expandVars replaces ${name} by the value of the first source which has
+// it. Like in the shell, ${name:-default} is replaced by default if none
+// has it or the value is empty. Unknown variables without default are
+// kept, so the shell can still expand them.
+func expandVars(s string, sources ...map[string]string) string {
+	return varPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if m == "$${" {
+			return "${"
+		}
+		sub := varPattern.FindStringSubmatch(m)
+		for _, src := range sources {
+			if v, ok := src[sub[1]]; ok {
+				if v == "" && sub[2] != "" {
+					return sub[3]
+				}
+				return v
+			}
+		}
+		if sub[2] != "" {
+			return sub[3]
+		}
+		return m
+	})
+}
+
+// interpolate expands the variables in cmd, args, dir and env values of all
+// commands and in the top-level env. Variables are looked up in [vars],
+// then in the env of the command and finally in the environment of
+// rousego. Values in [vars] can only use the environment, env values can
+// use [vars] and the environment but no other env values.
+func (cfg *cfgMain) interpolate() {
+	osEnv := environment()
+
+	vars := make(map[string]string)
+	for k, v := range cfg.Vars {
+		vars[k] = expandVars(v, osEnv)
+	}
+	cfg.Vars = vars
+
+	expandMap := func(m map[string]string) map[string]string {
+		if m == nil {
+			return nil
+		}
+		m = maps.Clone(m)
+		for k, v := range m {
+			m[k] = expandVars(v, vars, osEnv)
+		}
+		return m
+	}
+	cfg.Env = expandMap(cfg.Env)
+
+	for i := range cfg.Cmds {
+		c := &cfg.Cmds[i]
+		c.Env = expandMap(c.Env)
+		// the env of the command as it is merged in validate
+		env := mergeMap(cfg.Env, c.Env)
+
+		c.Cmd = expandVars(c.Cmd, vars, env, osEnv)
+		c.Dir = expandVars(c.Dir, vars, env, osEnv)
+		c.Args = slices.Clone(c.Args)
+		for j, arg := range c.Args {
+			c.Args[j] = expandVars(arg, vars, env, osEnv)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package rousego
+
+import "testing"
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"host": "localhost", "empty": ""}
+	env := map[string]string{"host": "example.com", "PORT": "8080"}
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"plain", "go run .", "go run ."},
+		{"first source wins", "${host}", "localhost"},
+		{"second source", "${host}:${PORT}", "localhost:8080"},
+		{"empty value", "[${empty}]", "[]"},
+		{"empty value with default", "${empty:-fallback}", "fallback"},
+		{"unknown is kept", "${HOME}", "${HOME}"},
+		{"default", "${missing:-fallback}", "fallback"},
+		{"empty default", "[${missing:-}]", "[]"},
+		{"default not used", "${PORT:-80}", "8080"},
+		{"shell variable", "$PORT", "$PORT"},
+		{"escape", "$${host}", "${host}"},
+		{"escape before variable", "$${host}${host}", "${host}localhost"},
+		{"invalid name", "${1x}", "${1x}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandVars(tt.s, vars, env); got != tt.want {
+				t.Errorf("expandVars(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
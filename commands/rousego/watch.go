@@ -0,0 +1,191 @@
+package rousego
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const defaultWatchDebounce = 300 * time.Millisecond
+
+// cfgWatch restarts a process when one of the watched files changes
+type cfgWatch struct {
+	// Paths are files or directories which are watched recursively
+	Paths []string `toml:"paths,omitempty"`
+	// Include and Exclude are glob patterns matched against the file name
+	// and the path relative to the watched directory. Without Include all
+	// files match.
+	Include []string `toml:"include,omitempty"`
+	Exclude []string `toml:"exclude,omitempty"`
+	// Debounce is the time without further changes before the restart
+	Debounce duration `toml:"debounce,omitempty"`
+}
+
+func (w *cfgWatch) validate(label string) error {
+	if len(w.Paths) == 0 {
+		w.Paths = []string{"."}
+	}
+	for _, pattern := range append(append([]string{}, w.Include...), w.Exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("[%s] invalid watch pattern %q: %w", label, pattern, err)
+		}
+	}
+	if w.Debounce.Duration <= 0 {
+		w.Debounce.Duration = defaultWatchDebounce
+	}
+	return nil
+}
+
+// matches reports whether a change of path restarts the process. rel is the
+// path relative to the watched directory.
+func (w *cfgWatch) matches(path, rel string) bool {
+	match := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(pattern, rel); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if match(w.Exclude) {
+		return false
+	}
+	return len(w.Include) == 0 || match(w.Include)
+}
+
+// watch restarts the process on matching file changes until ctx is done or
+// unwatch is called
+func (p *process) watch(ctx context.Context) error {
+	w := p.cfg.Watch
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	ctx, p.unwatch = context.WithCancel(ctx)
+
+	// roots maps every watched directory to the configured path it belongs
+	// to, files holds configured paths which are single files
+	roots := make(map[string]string)
+	files := make(map[string]bool)
+	addTree := func(root, dir string) {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			// .git, .rousego and friends change all the time
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if err := watcher.Add(path); err != nil {
+				slog.Warn("Cannot watch " + path + " for " + p.label() + ": " + fmt.Sprint(err))
+				return nil
+			}
+			roots[path] = root
+			return nil
+		})
+	}
+	for _, path := range w.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			watcher.Close()
+			return fmt.Errorf("%s cannot watch %s: %w", p.label(), path, err)
+		}
+		if info.IsDir() {
+			addTree(path, path)
+			continue
+		}
+		// editors replace files on save, so the directory is watched instead
+		files[path] = true
+		if err := watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+			return fmt.Errorf("%s cannot watch %s: %w", p.label(), path, err)
+		}
+	}
+
+	relevant := func(event fsnotify.Event) bool {
+		if files[event.Name] {
+			return true
+		}
+		root, ok := roots[filepath.Dir(event.Name)]
+		if !ok {
+			return false
+		}
+		if event.Has(fsnotify.Create) {
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+				addTree(root, event.Name)
+				return false
+			}
+		}
+		rel, _ := filepath.Rel(root, event.Name)
+		return w.matches(event.Name, rel)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var (
+			timer   <-chan time.Time
+			changed []string
+		)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod || !relevant(event) {
+					continue
+				}
+				if !slices.Contains(changed, event.Name) {
+					changed = append(changed, event.Name)
+				}
+				timer = time.After(w.Debounce.Duration)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Watching for " + p.label() + ": " + fmt.Sprint(err))
+			case <-timer:
+				timer = nil
+				p.changed(changed)
+				changed = nil
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// changed restarts the process after files changed. A process stopped by the
+// user stays stopped.
+func (p *process) changed(files []string) {
+	p.mu.Lock()
+	state := p.State
+	p.mu.Unlock()
+	if state == stateStopped || state == stateStopping || state == stateWaiting {
+		return
+	}
+
+	file := files[0]
+	if rel, err := filepath.Rel(p.cfg.watchBase, file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = rel
+	}
+	reason := "(changed: " + file
+	if len(files) > 1 {
+		reason += " and " + strconv.Itoa(len(files)-1) + " more"
+	}
+	p.restart(reason + ")")
+}
@@ -0,0 +1,31 @@
+package rousego
+
+import "testing"
+
+func TestCfgWatchMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		rel     string
+		want    bool
+	}{
+		{"everything", nil, nil, "main.go", true},
+		{"include name", []string{"*.go"}, nil, "cmd/main.go", true},
+		{"include misses", []string{"*.go"}, nil, "README.md", false},
+		{"include relative path", []string{"cmd/*"}, nil, "cmd/main.go", true},
+		{"relative path misses", []string{"cmd/*"}, nil, "internal/cmd.go", false},
+		{"exclude name", nil, []string{"*_test.go"}, "pkg/x_test.go", false},
+		{"exclude wins", []string{"*.go"}, []string{"*_test.go"}, "x_test.go", false},
+		{"exclude relative path", []string{"*.go"}, []string{"vendor/*/*"}, "vendor/lib/a.go", false},
+		{"not excluded", []string{"*.go"}, []string{"*_test.go"}, "x.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &cfgWatch{Include: tt.include, Exclude: tt.exclude}
+			if got := w.matches("/src/"+tt.rel, tt.rel); got != tt.want {
+				t.Errorf("matches(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
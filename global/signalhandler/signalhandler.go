@@ -8,57 +8,67 @@ import (
 	"syscall"
 )
 
-func Init(ctx context.Context) (chan os.Signal, context.CancelFunc) {
+// Handler is called for a signal which does not stop the program. ctx is the
+// context returned by Init.
+type Handler func(ctx context.Context, s os.Signal)
 
-	// trap Ctrl+C and call cancel on the context
+// Init returns a context which is cancelled as soon as SIGINT, SIGTERM or
+// SIGQUIT is received. The signals in handlers call their handler instead,
+// each in its own goroutine. The returned function stops trapping signals
+// and cancels the context.
+func Init(ctx context.Context, handlers map[os.Signal]Handler) (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(ctx)
 
 	c := make(chan os.Signal, 1)
-	signal.Notify(c,
-		os.Interrupt,
-		syscall.SIGHUP,
+	signals := []os.Signal{
 		syscall.SIGINT,
 		syscall.SIGTERM,
-		syscall.SIGQUIT)
+		syscall.SIGQUIT,
+	}
+	for s := range handlers {
+		signals = append(signals, s)
+	}
+	signal.Notify(c, signals...)
 
 	// Starting signal handler
-	go signalHandler(cancel, c)
+	go signalHandler(ctx, cancel, c, handlers)
 
-	return c, cancel
+	return ctx, func() {
+		signal.Stop(c)
+		cancel()
+	}
 }
 
-func signalHandler(cancel context.CancelFunc, c chan os.Signal) {
+func signalHandler(ctx context.Context, cancel context.CancelFunc, c chan os.Signal, handlers map[os.Signal]Handler) {
 	for {
-		s := <-c
-		switch s {
-		// kill -SIGHUP XXXX
-		case syscall.SIGHUP:
-			slog.Warn("hungup")
-			cancel()
-			// exit_chan <- true
+		var s os.Signal
+		select {
+		case s = <-c:
+		case <-ctx.Done():
+			return
+		}
+
+		if h, ok := handlers[s]; ok {
+			go h(ctx, s)
+			continue
+		}
 
+		switch s {
 		// kill -SIGINT XXXX or Ctrl+c
 		case syscall.SIGINT:
 			slog.Warn("Ctrl-C received")
-			cancel()
-			// exit_chan <- true
 
 		// kill -SIGTERM XXXX
 		case syscall.SIGTERM:
-			slog.Warn("force stop kill 9")
-			cancel()
-			// exit_chan <- true
+			slog.Warn("SIGTERM received")
 
 		// kill -SIGQUIT XXXX
 		case syscall.SIGQUIT:
-			slog.Warn("stop and core dump")
-			cancel()
-			// exit_chan <- true
+			slog.Warn("SIGQUIT received")
 
 		default:
-			slog.Warn("Unknown signal.")
-			cancel()
-			// exit_chan <- true
+			slog.Warn("Unknown signal: " + s.String())
 		}
+		cancel()
 	}
 }